@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
+)
+
+// batchOptions controls a directory conversion
+type batchOptions struct {
+	jsonLines bool // Report each file as a line of JSON instead of text
+
+	walk   i2pkeys.WalkOptions // Which files are converted
+	output outputOptions       // How outputs are written
+	jobs   int                 // Number of files converted at once
+
+	// Names each output, see expandOutputTemplate; outputs must stay inside the directory
+	template string
+
+	// Leave inputs alone whose output is at least as new, and replace outputs that are older
+	skipExisting bool
+}
+
+// batchResult is a file's outcome as printed, one line of text or JSON per file
+type batchResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Output string `json:"output,omitempty"`
+	Reason string `json:"reason,omitempty"` // Why a file was skipped
+	Error  string `json:"error,omitempty"`
+}
+
+// newBatchResult converts a library result for printing
+func newBatchResult(result i2pkeys.FileResult) batchResult {
+	printed := batchResult{Path: result.Path, Status: string(result.Status), Output: result.Output, Reason: result.Reason}
+	if result.Err != nil {
+		printed.Error = result.Err.Error()
+	}
+	return printed
+}
+
+// String formats the result as a line of the text report
+func (r batchResult) String(dryRun bool) string {
+	switch i2pkeys.FileStatus(r.Status) {
+	case i2pkeys.StatusConverted:
+		return fmt.Sprintf("CONVERTED %s -> %s", r.Path, r.Output)
+	case i2pkeys.StatusWouldConvert:
+		return fmt.Sprintf("WOULD CONVERT %s -> %s", r.Path, r.Output)
+	case i2pkeys.StatusUpToDate:
+		return fmt.Sprintf("UP TO DATE %s -> %s", r.Path, r.Output)
+	case i2pkeys.StatusSkipped:
+		if dryRun {
+			return fmt.Sprintf("ALREADY CORRECT, SKIP %s", r.Path)
+		}
+		return fmt.Sprintf("SKIPPED %s: %s", r.Path, r.Reason)
+	case i2pkeys.StatusLinkSkipped:
+		return fmt.Sprintf("SKIPPED %s: %s", r.Path, r.Reason)
+	case i2pkeys.StatusNotKey:
+		return fmt.Sprintf("NOT A KEY %s: %s", r.Path, r.Error)
+	default:
+		return fmt.Sprintf("FAILED %s: %s", r.Path, r.Error)
+	}
+}
+
+// runBatch converts a directory of key files, stopping on Ctrl-C, and returns the exit code
+func runBatch(dir string, opts batchOptions) int {
+	// Ctrl-C stops the run between files
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// With JSON lines on stdout the totals go to stderr, keeping stdout one object per line.
+	// A quiet run prints only the outputs, with failures on stderr.
+	report := io.Writer(os.Stdout)
+	switch {
+	case opts.output.quiet:
+		report = io.Discard
+	case opts.jsonLines:
+		report = os.Stderr
+	}
+
+	// Each file is reported as soon as it is done; ConvertDir makes one call at a time
+	existing := false
+	progress := func(result i2pkeys.FileResult) {
+		existing = existing || errors.Is(result.Err, i2pkeys.ErrOutputExists)
+		printFileResult(result, opts.output, opts.jsonLines)
+	}
+
+	summary, err := i2pkeys.ConvertDir(ctx, dir, i2pkeys.DirOptions{
+		Options:      opts.output.file,
+		Walk:         opts.walk,
+		Jobs:         opts.jobs,
+		Force:        opts.output.force,
+		DryRun:       opts.output.dryRun,
+		SkipExisting: opts.skipExisting,
+		OutputPath: func(path string) string {
+			return expandOutputTemplate(opts.template, path, "")
+		},
+		Progress: progress,
+	})
+	interrupted := errors.Is(err, context.Canceled)
+	if interrupted {
+		fmt.Fprintln(report, "\nInterrupted")
+	} else if err != nil {
+		return fail(os.Stderr, &ioError{err})
+	}
+
+	fmt.Fprintf(report, "\nConverted: %d, skipped (already correct): %d, failed: %d\n", summary.Converted, summary.Skipped, summary.Failed)
+	if summary.UpToDate > 0 {
+		fmt.Fprintf(report, "Up to date: %d\n", summary.UpToDate)
+	}
+	if summary.LinksSkipped > 0 {
+		fmt.Fprintf(report, "Symbolic links not followed: %d (use -follow to follow them)\n", summary.LinksSkipped)
+	}
+	if existing {
+		fmt.Fprintln(report, "Use -force to overwrite existing output files")
+	}
+	if summary.Failed > 0 || interrupted {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// printFileResult prints one file of a directory run as text, a line of JSON, or in quiet mode
+// only its output path
+func printFileResult(result i2pkeys.FileResult, output outputOptions, jsonLines bool) {
+	if output.quiet {
+		printQuietResult(result, output.dryRun)
+		return
+	}
+	printed := newBatchResult(result)
+	if !jsonLines {
+		fmt.Println(printed.String(output.dryRun))
+		return
+	}
+	encoded, err := json.Marshal(printed)
+	if err != nil {
+		encoded = []byte(fmt.Sprintf(`{"path":%q,"status":"failed","error":%q}`, result.Path, err.Error()))
+	}
+	fmt.Println(string(encoded))
+}
+
+// runWatch converts key files into out as they appear in dir, until Ctrl-C or SIGTERM, and
+// returns the exit code. Without out the keys go to <dir>.formatted beside dir.
+func runWatch(dir, out string, interval time.Duration, opts batchOptions) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dir = filepath.Clean(dir)
+	if out == "" {
+		out = dir + ".formatted"
+	}
+
+	// As with -dir, JSON lines keep stdout to themselves
+	status := io.Writer(os.Stdout)
+	switch {
+	case opts.output.quiet:
+		status = io.Discard
+	case opts.jsonLines:
+		status = os.Stderr
+	}
+	fmt.Fprintf(status, "Watching %s, converting into %s every %s (Ctrl-C to stop)\n", dir, out, interval)
+
+	err := i2pkeys.WatchDir(ctx, dir, i2pkeys.WatchOptions{
+		Options:   opts.output.file,
+		Walk:      opts.walk,
+		OutputDir: out,
+		Interval:  interval,
+		Progress: func(result i2pkeys.FileResult) {
+			printFileResult(result, opts.output, opts.jsonLines)
+		},
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(status, "Stopped")
+		return exitOK
+	}
+	return fail(os.Stderr, &ioError{err})
+}
+
+// runZip converts every key in a zip archive, into a new archive when out ends in .zip and
+// into the directory out otherwise, and returns the exit code
+func runZip(archivePath, out string, output outputOptions) int {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return fail(os.Stderr, &ioError{fmt.Errorf("failed to read input file: %w", err)})
+	}
+	if out == "" {
+		out = strings.TrimSuffix(archivePath, filepath.Ext(archivePath)) + ".formatted.zip"
+	}
+	toArchive := out == "-" || strings.EqualFold(filepath.Ext(out), ".zip")
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	write := func(name string, formatted []byte) error {
+		if toArchive {
+			entry, err := archive.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = entry.Write(formatted)
+			return err
+		}
+		return writeZipEntry(filepath.Join(out, filepath.FromSlash(name)), formatted, output)
+	}
+
+	summary, err := i2pkeys.ConvertZip(data, output.file, write)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	// Progress goes to stderr when the new archive is written to stdout
+	report := statusWriter(out)
+	if output.quiet {
+		report = io.Discard
+	}
+	for _, result := range summary.Files {
+		if result.Status == i2pkeys.StatusConverted && output.dryRun {
+			result.Status = i2pkeys.StatusWouldConvert
+		}
+		if !toArchive && result.Output != "" {
+			result.Output = filepath.Join(out, filepath.FromSlash(result.Output))
+		}
+		switch {
+		case !output.quiet:
+			fmt.Fprintln(report, newBatchResult(result).String(output.dryRun))
+		case toArchive:
+			// Only the archive is printed, once it is written
+			if result.Status == i2pkeys.StatusFailed {
+				printQuietResult(result, output.dryRun)
+			}
+		default:
+			// Entries written unchanged are outputs too
+			if result.Status == i2pkeys.StatusSkipped {
+				result.Status = i2pkeys.StatusConverted
+			}
+			printQuietResult(result, output.dryRun)
+		}
+	}
+
+	if toArchive {
+		if err := archive.Close(); err != nil {
+			return fail(os.Stderr, fmt.Errorf("failed to write zip archive: %w", err))
+		}
+		// The archive holds keys in the clear and gets a key file's permissions, but isn't gzipped again
+		archiveOutput := output
+		archiveOutput.file.Gzip = false
+		if err := writeOutput(out, buffer.Bytes(), archiveOutput); err != nil {
+			return fail(os.Stderr, err)
+		}
+		output.printResult(out)
+	}
+
+	fmt.Fprintf(report, "\nConverted: %d, already correct: %d, not keys: %d, failed: %d\n",
+		summary.Converted, summary.Skipped, summary.NotKeys, summary.Failed)
+	if toArchive && !output.dryRun {
+		fmt.Fprintf(report, "Written to %s\n", out)
+	}
+	if summary.Failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// printQuietResult reports a file of a quiet run: its output on stdout if it was converted,
+// the failure on stderr if it failed, and nothing otherwise
+func printQuietResult(result i2pkeys.FileResult, dryRun bool) {
+	switch result.Status {
+	case i2pkeys.StatusConverted, i2pkeys.StatusWouldConvert:
+		fmt.Println(result.Output)
+	case i2pkeys.StatusFailed:
+		fmt.Fprintln(os.Stderr, newBatchResult(result).String(dryRun))
+	}
+}
+
+// writeZipEntry writes one key of an archive into the output directory
+func writeZipEntry(path string, formatted []byte, output outputOptions) error {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("output path '%s' is a symbolic link, refusing to write through it", path)
+		}
+		if !output.force {
+			return fmt.Errorf("output file '%s' already exists (use -force to overwrite)", path)
+		}
+	}
+	if output.dryRun {
+		return nil
+	}
+	return i2pkeys.WriteKeyFile(path, formatted, output.file)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
+)
+
+// command is a subcommand of the CLI with its own flag set
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// subcommands lists the commands in the order the help text shows them
+func subcommands() []command {
+	return []command{
+		{"convert", "Convert a key file, or a directory of them, to the two-line format", convertCommand},
+		{"check", "Check whether a key file is already in the two-line format", checkCommand},
+		{"address", "Print the .b32.i2p address of a key", addressCommand},
+		{"inspect", "Report on the structure of a key without writing anything", inspectCommand},
+		{"validate", "Report on each integrity check of a formatted key", validateCommand},
+	}
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range subcommands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage writes the top-level help text listing every subcommand
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "I2P Keys Converter - Format I2P keys for Go I2P libraries\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range subcommands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s help <command>' for the options of a command.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nExit codes: 0 success, 1 other error, 2 usage, 3 I/O, 4 not a recognised key format, 5 failed validation\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  Convert binary key file:   %s convert -in keys.dat -out keys.dat.formatted\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Check key file format:     %s check -strict keys.dat\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Print base32 address:      %s address keys.dat\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Print destination hash:    %s address -hash keys.dat\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Describe a key:            %s inspect keys.dat\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Validate key integrity:    %s validate keys.dat.formatted\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Convert a directory:       %s convert -dir keys/ -recursive\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Convert a zip archive:     %s convert -zip keys.zip -out formatted/\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Watch an incoming folder:  %s convert -watch incoming/ -out formatted/\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  Convert in a pipeline:     cat keys.dat | %s convert -in - -out -\n", os.Args[0])
+}
+
+// helpCommand prints the help text of a subcommand, or the top-level help
+func helpCommand(args []string) int {
+	if len(args) > 0 {
+		if cmd, ok := findCommand(args[0]); ok {
+			return cmd.run([]string{"-h"})
+		}
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printUsage()
+		return exitUsage
+	}
+	printUsage()
+	return exitOK
+}
+
+// newFlagSet creates the flag set of a subcommand with help text scoped to it
+func newFlagSet(name, usage, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.BoolFunc("debug", "Log each detection and parsing decision to stderr", enableDebug)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", summary)
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n\n", os.Args[0], name, usage)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// enableDebug sends the library's decision log to stderr, for -debug
+func enableDebug(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		i2pkeys.SetDebugLogger(log.New(os.Stderr, "debug: ", 0))
+	} else {
+		i2pkeys.SetDebugLogger(nil)
+	}
+	return nil
+}
+
+// addOutputFlags registers the flags controlling how output files are written
+func addOutputFlags(fs *flag.FlagSet) func() outputOptions {
+	force := fs.Bool("force", false, "Overwrite the output file if it already exists, and accept inputs over 64 KB")
+	private := fs.Bool("private", true, "Create output directories readable only by the owner (0700 instead of 0755)")
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dryrun", false, "Report what would be converted without writing anything")
+	fs.BoolVar(&dryRun, "n", false, "Shorthand for -dryrun")
+	compress := fs.Bool("gzip", false, "Gzip-compress the output (automatic when the output file ends in .gz)")
+	quiet := fs.Bool("q", false, "Print only the output path on success, for scripts; warnings and errors go to stderr")
+	noValidate := fs.Bool("no-validate", false, "Skip the certificate, length and destination line checks of each converted key, for speed (ignored with -strict)")
+
+	return func() outputOptions {
+		output := outputOptions{force: *force, dryRun: dryRun, quiet: *quiet}
+		output.file.Gzip = *compress
+		output.file.Validate = !*noValidate
+		if !*private {
+			output.file.DirPerm = 0755
+		}
+		return output
+	}
+}
+
+// parseSigningTypes reads the comma-separated list of an -allow-sigtype flag
+func parseSigningTypes(list string) ([]uint16, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var codes []uint16
+	for _, name := range strings.Split(list, ",") {
+		code, err := i2pkeys.ParseSigningType(name)
+		if err != nil {
+			return nil, fmt.Errorf("-allow-sigtype: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// inputArg returns the -in flag, or the single positional argument when -in is not given
+func inputArg(fs *flag.FlagSet, inputFile string) string {
+	if inputFile == "" && fs.NArg() == 1 {
+		return fs.Arg(0)
+	}
+	return inputFile
+}
+
+// requireInput prints the subcommand's usage when no input file was given
+func requireInput(fs *flag.FlagSet, inputFile string) bool {
+	if inputFile != "" {
+		return true
+	}
+	fmt.Fprintln(os.Stderr, "Error: Input file (-in) is required")
+	fs.Usage()
+	return false
+}
+
+// convertCommand implements "convert"
+func convertCommand(args []string) int {
+	fs := newFlagSet("convert", "-in keyfile [-out outputfile] [options]\n       "+os.Args[0]+" convert -dir directory [-recursive] [options]\n       "+os.Args[0]+" convert -zip archive.zip [-out directory|archive.zip] [options]\n       "+os.Args[0]+" convert -watch directory [-out directory] [-interval 2s] [options]",
+		"Convert I2P key files to the two-line format required by Go I2P")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin")
+	keyArg := fs.String("key", "", "Key data to convert, given directly instead of -in; written to stdout by default")
+	outputFile := fs.String("out", "", "Path to save the formatted key, or - for stdout (default: beside the input, or in $I2PKEYS_OUT_DIR)")
+	verbose := fs.Bool("v", false, "Verbose output with key details")
+	preview := fs.Int("preview", defaultPreviewLength, "Characters of each key line shown by -v, 0 for the whole line")
+	strict := fs.Bool("strict", false, "Reject keys written in the standard Base64 alphabet, and validate each converted key even with -no-validate")
+	impl := fs.String("impl", "", "Private key layout to expect: java or i2pd (default: autodetect)")
+	allKeys := fs.Bool("all", false, "Convert every key in a multi-key file, not just the first")
+	jsonOutput := fs.Bool("json", false, "Print a JSON description of the key instead of text, or with -dir one JSON line per file")
+	reverse := fs.Bool("reverse", false, "Convert a two-line formatted key back to the raw binary keypair")
+	pubOnly := fs.Bool("pubonly", false, "Write only the public destination line to <input>.destination, without the private key")
+	single := fs.Bool("single", false, "Write the full keypair as one I2P Base64 line, without the destination line")
+	split := fs.Bool("split", false, "Write the destination to <input>.dest and the private keys to <input>.priv, each as one I2P Base64 line")
+	redact := fs.Bool("redact", false, "Write only the destination to <input>.pub, safe to publish")
+	pemOutput := fs.Bool("pem", false, "Write the destination and full keypair as PEM blocks")
+	inFormat := fs.String("informat", "", "Encoding of the input: binary, i2pb64, stdb64 or hex (default: autodetect)")
+	fingerprint := fs.Bool("fingerprint", false, "Also write the SHA-256 of the formatted key to <output>.sha256, or print it for stdout")
+	allowSigTypes := fs.String("allow-sigtype", "", "Refuse keys whose signing type is not in this comma-separated list, such as ed25519,ecdsa-p256")
+	expectAddress := fs.String("expect-address", "", "Refuse to write the key unless it belongs to this .b32.i2p address")
+	verifyLen := fs.Bool("verify-length", false, "Refuse keys whose length does not match the key types in their certificate")
+	transcode := fs.Bool("transcode", false, "Rewrite each Base64 line between the standard and I2P alphabets")
+	repair := fs.Bool("repair", false, "Rebuild a damaged destination line from the full key line of a two-line key, swapping lines that are in reverse order")
+	template := fs.String("template", defaultOutputTemplate, "Output name when -out is not given, from {dir}, {base}, {name} and {ext} of the input")
+	batchDir := fs.String("dir", "", "Convert every key file in a directory")
+	zipArchive := fs.String("zip", "", "Convert every key in a zip archive, into a new archive if -out ends in .zip and a directory otherwise (default: <archive>.formatted.zip)")
+	recursive := fs.Bool("recursive", false, "Descend into subdirectories when using -dir")
+	follow := fs.Bool("follow", false, "Follow symbolic links out of the directory when using -dir")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "Number of files to convert at once when using -dir")
+	skipExisting := fs.Bool("skip-existing", false, "With -dir, skip files whose output is newer and reconvert those whose output is older")
+	watchDir := fs.String("watch", "", "Keep converting key files as they appear in a directory, into -out (default: <directory>.formatted), until stopped")
+	interval := fs.Duration("interval", i2pkeys.DefaultWatchInterval, "How often -watch scans; a file must be unchanged for this long before it is converted")
+	outputFlags := addOutputFlags(fs)
+	fs.Parse(args)
+
+	output := outputFlags()
+	if *strict {
+		output.file.Validate = true
+	}
+	if output.quiet && (*verbose || *jsonOutput) {
+		fmt.Fprintln(os.Stderr, "Error: -q can't be used with -v or -json")
+		return exitUsage
+	}
+	allowed, err := parseSigningTypes(*allowSigTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitUsage
+	}
+	if *expectAddress != "" {
+		if _, err := i2pkeys.ParseBase32Address(*expectAddress); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -expect-address: %s\n", err)
+			return exitUsage
+		}
+	}
+	if (*expectAddress != "" || allowed != nil) && (*batchDir != "" || *zipArchive != "" || *watchDir != "" || *reverse || *pubOnly || *single || *redact || *split || *pemOutput || *repair || *transcode) {
+		fmt.Fprintln(os.Stderr, "Error: -expect-address and -allow-sigtype only work when converting a single key file")
+		return exitUsage
+	}
+	if *watchDir != "" {
+		if *batchDir != "" || *zipArchive != "" || *inputFile != "" || *keyArg != "" || output.dryRun || *reverse || *pubOnly || *single || *redact || *split || *pemOutput || *repair || *transcode {
+			fmt.Fprintln(os.Stderr, "Error: -watch only works for a plain conversion, without -in, -key, -dir, -zip or -n")
+			return exitUsage
+		}
+		return runWatch(*watchDir, *outputFile, *interval, batchOptions{
+			walk:      i2pkeys.WalkOptions{Recursive: *recursive, FollowLinks: *follow},
+			output:    output,
+			jsonLines: *jsonOutput,
+		})
+	}
+	if *zipArchive != "" {
+		return runZip(*zipArchive, *outputFile, output)
+	}
+	if *batchDir != "" {
+		return runBatch(*batchDir, batchOptions{
+			walk:      i2pkeys.WalkOptions{Recursive: *recursive, FollowLinks: *follow},
+			output:    output,
+			jobs:      *jobs,
+			template:  *template,
+			jsonLines: *jsonOutput,
+
+			skipExisting: *skipExisting,
+		})
+	}
+
+	in := inputArg(fs, *inputFile)
+	if *keyArg != "" {
+		if in != "" {
+			fmt.Fprintln(os.Stderr, "Error: -key and -in can't be used together")
+			return exitUsage
+		}
+		if *reverse || *pubOnly || *single || *redact || *split || *pemOutput || *repair || *transcode {
+			fmt.Fprintln(os.Stderr, "Error: -key only works for a plain conversion, use -in - and stdin instead")
+			return exitUsage
+		}
+		// The key has no file to be written beside
+		in = "-"
+	}
+	if !requireInput(fs, in) {
+		return exitUsage
+	}
+
+	switch *inFormat {
+	case "", i2pkeys.InputBinary, i2pkeys.InputI2PBase64, i2pkeys.InputStdBase64, i2pkeys.InputHex:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -informat %q, expected binary, i2pb64, stdb64 or hex\n", *inFormat)
+		return exitUsage
+	}
+
+	switch {
+	case *reverse:
+		return runExport(in, *outputFile, reverseExport, output)
+	case *pubOnly:
+		return runExport(in, *outputFile, pubOnlyExport, output)
+	case *single:
+		return runExport(in, *outputFile, singleExport, output)
+	case *redact:
+		return runExport(in, *outputFile, redactExport, output)
+	case *split:
+		return runSplit(in, *outputFile, output)
+	case *pemOutput:
+		return runExport(in, *outputFile, pemExport, output)
+	case *repair:
+		return runExport(in, *outputFile, repairExport, output)
+	case *transcode:
+		return runExport(in, *outputFile, transcodeExport, output)
+	default:
+		return runConvert(convertConfig{
+			inputFile:  in,
+			keyArg:     *keyArg,
+			outputFile: *outputFile,
+			verbose:    *verbose,
+			strict:     *strict,
+			impl:       *impl,
+			allKeys:    *allKeys,
+			jsonOutput: *jsonOutput,
+			verifyLen:  *verifyLen,
+			address:    *expectAddress,
+			sigTypes:   allowed,
+			sha256:     *fingerprint,
+			inFormat:   *inFormat,
+			preview:    *preview,
+			template:   *template,
+			output:     output,
+		})
+	}
+}
+
+// checkCommand implements "check"
+func checkCommand(args []string) int {
+	fs := newFlagSet("check", "[-strict] keyfile\n       "+os.Args[0]+" check -dir directory [-recursive] [-strict]",
+		"Check whether key files are already in the two-line format")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin")
+	strict := fs.Bool("strict", false, "Also validate the destination's certificate, check line 1 is the start of line 2 and reject standard-Base64 keys")
+	checkDir := fs.String("dir", "", "Check every file in a directory and print a tally")
+	recursive := fs.Bool("recursive", false, "Descend into subdirectories when using -dir")
+	follow := fs.Bool("follow", false, "Follow symbolic links out of the directory when using -dir")
+	fs.Parse(args)
+
+	if *checkDir != "" {
+		return runCheckDirectory(*checkDir, i2pkeys.WalkOptions{Recursive: *recursive, FollowLinks: *follow}, *strict)
+	}
+
+	in := inputArg(fs, *inputFile)
+	if !requireInput(fs, in) {
+		return exitUsage
+	}
+	return runCheck(in, *strict)
+}
+
+// addressCommand implements "address"
+func addressCommand(args []string) int {
+	fs := newFlagSet("address", "[-hash] keyfile", "Print the .b32.i2p address of a key")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin")
+	showHash := fs.Bool("hash", false, "Print the 32-byte destination hash as lowercase hex instead")
+	// The address is all this command prints, so -q is accepted for scripts that pass it everywhere
+	fs.Bool("q", false, "Print only the result (always the case here)")
+	fs.Parse(args)
+
+	in := inputArg(fs, *inputFile)
+	if !requireInput(fs, in) {
+		return exitUsage
+	}
+	return runAddress(in, *showHash)
+}
+
+// inspectCommand implements "inspect"
+func inspectCommand(args []string) int {
+	fs := newFlagSet("inspect", "[-json] keyfile\n       "+os.Args[0]+" inspect -compare keyfile keyfile",
+		"Report on the structure of a key without writing anything")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin")
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	compare := fs.Bool("compare", false, "Compare two key files field by field, exiting with 5 if they differ")
+	fs.Parse(args)
+
+	if *compare {
+		if fs.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Error: -compare needs two key files")
+			fs.Usage()
+			return exitUsage
+		}
+		return runCompare(fs.Arg(0), fs.Arg(1))
+	}
+
+	in := inputArg(fs, *inputFile)
+	if !requireInput(fs, in) {
+		return exitUsage
+	}
+	return runInspect(in, *jsonOutput)
+}
+
+// validateCommand implements "validate"
+func validateCommand(args []string) int {
+	fs := newFlagSet("validate", "[-allow-sigtype list] keyfile", "Report on each integrity check of a formatted key")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin")
+	allowSigTypes := fs.String("allow-sigtype", "", "Also fail keys whose signing type is not in this comma-separated list, such as ed25519,ecdsa-p256")
+	fs.Parse(args)
+
+	allowed, err := parseSigningTypes(*allowSigTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitUsage
+	}
+
+	in := inputArg(fs, *inputFile)
+	if !requireInput(fs, in) {
+		return exitUsage
+	}
+	return runValidate(in, allowed)
+}
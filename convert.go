@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
+)
+
+// Characters of each key line the verbose summary shows by default
+const defaultPreviewLength = 40
+
+// convertConfig holds the settings for converting a single key file
+type convertConfig struct {
+	inputFile  string
+	keyArg     string // Key data given on the command line, read instead of inputFile
+	outputFile string
+	verbose    bool
+	strict     bool
+	impl       string
+	allKeys    bool
+	jsonOutput bool
+	verifyLen  bool
+	address    string   // The .b32.i2p address the key must belong to, if set
+	sigTypes   []uint16 // Signing key types the key may have, any when nil
+	sha256     bool     // Record the SHA-256 of the output in <output>.sha256
+	inFormat   string
+	preview    int    // Characters of each key line shown in verbose mode, 0 for all
+	template   string // Names the output when outputFile is empty
+	output     outputOptions
+}
+
+// runConvert converts one key file to the two-line format and returns the exit code
+func runConvert(cfg convertConfig) int {
+	data, err := cfg.load()
+	if err == nil && i2pkeys.IsZip(data) {
+		// Archives are usually larger than a key, so catch them before the size check
+		code := fail(cfg.output.statusWriter(cfg.outputFile), i2pkeys.ErrZipArchive)
+		fmt.Fprintln(cfg.output.statusWriter(cfg.outputFile), "Use -zip to convert every key in it")
+		return code
+	}
+	if err == nil {
+		err = checkInputSize(data, cfg.output.force)
+	}
+	if err != nil {
+		return fail(cfg.output.statusWriter(cfg.outputFile), err)
+	}
+
+	// Counted as keys, a backwards two-line key looks like a keypair followed by a destination
+	if cfg.inFormat == "" && i2pkeys.LinesSwapped(data) {
+		code := fail(cfg.output.statusWriter(cfg.outputFile), i2pkeys.ErrSwappedLines)
+		fmt.Fprintln(cfg.output.statusWriter(cfg.outputFile), "Use -repair to put the lines in order")
+		return code
+	}
+
+	// A name that says text over binary bytes, or the reverse, suggests the wrong file was given
+	if cfg.keyArg == "" && cfg.inFormat == "" {
+		if warning := extensionMismatch(cfg.inputFile, data); warning != "" {
+			fmt.Fprintf(cfg.output.statusWriter(cfg.outputFile), "Warning: %s\n", warning)
+		}
+	}
+
+	// A declared input encoding is decoded once, up front, so nothing below guesses at it
+	inputFormat := i2pkeys.InputAuto
+	if cfg.inFormat != "" {
+		if data, err = i2pkeys.DecodeInput(data, cfg.inFormat); err != nil {
+			return fail(cfg.output.statusWriter(cfg.outputFile), err)
+		}
+		inputFormat = i2pkeys.InputBinary
+	}
+
+	// Set default output file if not specified
+	if cfg.outputFile == "" {
+		cfg.outputFile = templateOutputPath(cfg.inputFile, cfg.template)
+	}
+
+	status := cfg.output.statusWriter(cfg.outputFile)
+
+	// In JSON mode the key description replaces the progress text, and quiet mode has none
+	progress := status
+	if cfg.jsonOutput || cfg.output.quiet {
+		progress = io.Discard
+	}
+
+	// Print operation info
+	fmt.Fprintf(progress, "Formatting I2P key file: %s\n", cfg.inputFile)
+	fmt.Fprintf(progress, "Output file: %s\n", cfg.outputFile)
+
+	// Keys saved on Windows are rewritten with LF line endings
+	if i2pkeys.IsCorrectFormat(string(data)) && bytes.Contains(data, []byte("\r\n")) {
+		fmt.Fprintln(status, "Warning: input has CRLF line endings, converting them to LF")
+	}
+
+	// Strict mode refuses keys pasted in the standard Base64 alphabet
+	if cfg.strict {
+		if err := i2pkeys.CheckAlphabet(string(data)); err != nil {
+			return fail(status, err)
+		}
+	}
+
+	// Make sure the private keys fit the requested implementation's layout
+	if cfg.impl != "" {
+		if _, err := i2pkeys.ParseKeysFor(data, cfg.impl); err != nil {
+			return fail(status, fmt.Errorf("key does not match the %s layout: %w", cfg.impl, err))
+		}
+	}
+
+	// Count the keys so none are dropped silently
+	keyPairs, _ := i2pkeys.ParseAllKeyPairs(data)
+	keyCount := len(keyPairs)
+
+	// Keys of an unknown type are split by the certificate length alone, which can't be checked
+	for _, keyPair := range keyPairs {
+		warnUnknownKeyTypes(status, keyPair.PublicKey)
+	}
+
+	// Catch truncated or concatenated keys before they are written as correct
+	if cfg.verifyLen {
+		keys := [][]byte{data}
+		if keyCount > 0 {
+			keys = keys[:0]
+			for _, keyPair := range keyPairs {
+				keys = append(keys, keyPair.FullData)
+			}
+		}
+		for _, key := range keys {
+			if err := i2pkeys.VerifyKeyLength(key); err != nil {
+				return fail(status, err)
+			}
+		}
+	}
+
+	// Convert the key data
+	var resultData []byte
+	switch {
+	case keyCount > 1 && cfg.allKeys:
+		resultData, err = i2pkeys.FormatAllKeys(data)
+	case keyCount > 1:
+		fmt.Fprintf(status, "Warning: input contains %d keys, only the first was converted (use -all to convert every key)\n", keyCount)
+		resultData, err = i2pkeys.ConvertKeys(keyPairs[0].FullData)
+	default:
+		resultData, err = i2pkeys.ConvertKeysAs(data, inputFormat)
+	}
+	if err != nil {
+		fmt.Fprintf(status, "Error: %s\n", err)
+		if errors.Is(err, i2pkeys.ErrNoPrivateKey) {
+			fmt.Fprintln(status, "Use -pubonly to write the destination on its own")
+		}
+		return exitCode(err)
+	}
+
+	// Check the structure of every key before it is written, unless -no-validate asked for speed
+	if cfg.output.file.Validate {
+		if err := i2pkeys.ValidateConverted(resultData); err != nil {
+			return fail(status, err)
+		}
+	}
+
+	// Keep keys of disallowed algorithms out of the new system, every key of a multi-key file included
+	if cfg.sigTypes != nil {
+		converted, err := i2pkeys.ParseAllKeyPairs(resultData)
+		if err != nil {
+			return fail(status, err)
+		}
+		for _, keyPair := range converted {
+			if err := i2pkeys.CheckSigningType(keyPair.PublicKey, cfg.sigTypes); err != nil {
+				return fail(status, err)
+			}
+		}
+	}
+
+	// Confirm this is the key the user meant before it is written anywhere
+	if cfg.address != "" {
+		if err := i2pkeys.VerifyAddress(resultData, cfg.address); err != nil {
+			return fail(status, err)
+		}
+		fmt.Fprintf(progress, "Key matches %s\n", cfg.address)
+	}
+
+	if err := writeOutput(cfg.outputFile, resultData, cfg.output); err != nil {
+		return fail(status, err)
+	}
+
+	// A dry run stops once the conversion is known to work
+	if cfg.output.dryRun {
+		switch {
+		case cfg.output.quiet:
+			cfg.output.printResult(cfg.outputFile)
+		case i2pkeys.IsCorrectFormat(string(data)):
+			fmt.Fprintf(status, "ALREADY CORRECT, SKIP %s\n", cfg.inputFile)
+		default:
+			fmt.Fprintf(status, "WOULD CONVERT %s -> %s\n", cfg.inputFile, cfg.outputFile)
+		}
+		return exitOK
+	}
+
+	if cfg.sha256 {
+		if err := writeFingerprint(status, cfg.outputFile, resultData, cfg.output); err != nil {
+			return fail(status, err)
+		}
+	}
+
+	// Multi-key output is a series of two-line blocks rather than a single key
+	if keyCount > 1 && cfg.allKeys {
+		fmt.Fprintf(progress, "Conversion successful - %d keys written as two-line blocks\n", keyCount)
+		cfg.output.printResult(cfg.outputFile)
+		return exitOK
+	}
+
+	// Verify the result
+	if !i2pkeys.IsCorrectFormat(string(resultData)) {
+		fmt.Fprintln(status, "Warning: Output file is not in the correct format")
+		return exitValidation
+	}
+	fmt.Fprintln(progress, "Conversion successful - key is now in the correct format")
+	cfg.output.printResult(cfg.outputFile)
+
+	// Describe the key as JSON if requested
+	if cfg.jsonOutput {
+		if err := printKeyJSON(status, resultData); err != nil {
+			return fail(status, err)
+		}
+		return exitOK
+	}
+
+	// Display additional information if verbose mode is enabled
+	if cfg.verbose {
+		hostname := ""
+		if keyCount > 0 {
+			hostname = keyPairs[0].Hostname
+		}
+		printKeyInfo(status, resultData, hostname, cfg.impl, cfg.preview)
+	}
+	return exitOK
+}
+
+// writeFingerprint records the SHA-256 of the formatted output in <output>.sha256, in the format
+// sha256sum -c reads, or prints it to w when the output went to stdout. The hash is of the
+// uncompressed key, so it stays comparable across re-runs whether or not -gzip was used.
+func writeFingerprint(w io.Writer, outputFile string, formatted []byte, output outputOptions) error {
+	sum := sha256.Sum256(formatted)
+	if outputFile == "-" {
+		fmt.Fprintf(w, "SHA-256: %x\n", sum)
+		return nil
+	}
+
+	line := fmt.Sprintf("%x  %s\n", sum, filepath.Base(outputFile))
+	output.file.Gzip = false
+	if err := writeOutput(outputFile+".sha256", []byte(line), output); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Fingerprint written to %s.sha256\n", outputFile)
+	return nil
+}
+
+// Environment variable naming the directory outputs go to when -out is not given
+const outputDirEnv = "I2PKEYS_OUT_DIR"
+
+// defaultOutputPath names the output in $I2PKEYS_OUT_DIR if it is set and otherwise beside the
+// input, or stdout when reading from stdin. An explicit -out takes precedence over both.
+func defaultOutputPath(inputFile, suffix string) string {
+	return templateOutputPath(inputFile, "{base}"+suffix)
+}
+
+// load returns the key data to convert, from the command line or the input file
+func (cfg convertConfig) load() ([]byte, error) {
+	if cfg.keyArg == "" {
+		return loadInput(cfg.inputFile)
+	}
+
+	// Arguments are readable by every user through the process list, and often saved in shell history
+	if keyPair, err := i2pkeys.ParseKeyPair([]byte(cfg.keyArg)); err == nil && len(keyPair.PrivateKey) > 0 {
+		fmt.Fprintln(os.Stderr, "Warning: -key holds a private key, which other users can see in the process list; prefer -in or stdin")
+	}
+	return []byte(cfg.keyArg), nil
+}
+
+// templateOutputPath is defaultOutputPath with the output named by a -template
+func templateOutputPath(inputFile, template string) string {
+	if inputFile == "-" {
+		return "-"
+	}
+	return expandOutputTemplate(template, inputFile, os.Getenv(outputDirEnv))
+}
+
+// printKeyJSON writes the JSON description of a two-line key
+func printKeyJSON(w io.Writer, formatted []byte) error {
+	keyPair, err := i2pkeys.ParseKeyPair(formatted)
+	if err != nil {
+		return err
+	}
+
+	description, err := json.Marshal(keyPair)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, string(description))
+	return nil
+}
+
+// printKeyInfo writes the human-readable summary of a two-line key shown in verbose mode,
+// previewing preview characters of each line
+func printKeyInfo(w io.Writer, formatted []byte, hostname, impl string, preview int) {
+	lines := bytes.Split(formatted, []byte("\n"))
+	if len(lines) < 2 {
+		return
+	}
+	publicKeyPreview := truncateString(string(lines[0]), preview)
+	fullKeyPreview := truncateString(string(lines[1]), preview)
+
+	fmt.Fprintln(w, "\nKey Information:")
+	fmt.Fprintf(w, "- Destination (public key): %s\n", publicKeyPreview)
+	fmt.Fprintf(w, "- Full key length: %d characters\n", len(lines[1]))
+	fmt.Fprintf(w, "- Full key preview: %s\n", fullKeyPreview)
+	if hostname != "" {
+		fmt.Fprintf(w, "- Hostname: %s\n", hostname)
+	}
+	if keyPair, err := i2pkeys.ParseKeyPair(formatted); err == nil {
+		if sigType, err := i2pkeys.SigningKeyType(keyPair.PublicKey); err == nil {
+			fmt.Fprintf(w, "- Signature type: %s\n", sigType)
+		} else {
+			fmt.Fprintf(w, "- Signature type: %s\n", err)
+		}
+	}
+	if offline, err := i2pkeys.ParseOfflineSignature(formatted); err == nil {
+		fmt.Fprintf(w, "- Offline signature: %s transient key, expires %s\n", offline.TransientKeyType, offline.Expires.Format(time.RFC3339))
+	}
+	implName := impl
+	if implName == "" {
+		if detected, err := i2pkeys.DetectImplementation(formatted); err == nil {
+			implName = detected + " (detected)"
+		}
+	}
+	if implName != "" {
+		fmt.Fprintf(w, "- Implementation: %s\n", implName)
+	}
+	fmt.Fprintln(w, "\nFormat: Two lines")
+	fmt.Fprintln(w, "- Line 1: Base64-encoded destination (public key)")
+	fmt.Fprintln(w, "- Line 2: Base64-encoded full keypair (public + private)")
+}
+
+// warnUnknownKeyTypes warns when a destination's certificate declares a key type code that
+// isn't in the registry
+func warnUnknownKeyTypes(w io.Writer, destination []byte) {
+	cert, err := i2pkeys.ParseCertificate(destination)
+	if err != nil {
+		return
+	}
+	if _, ok := i2pkeys.SupportedSigningTypes()[cert.SigningKeyType]; !ok {
+		fmt.Fprintf(w, "Warning: unknown signing key type %d, the key's layout can't be checked\n", cert.SigningKeyType)
+	}
+	if _, ok := i2pkeys.SupportedCryptoTypes()[cert.CryptoKeyType]; !ok {
+		fmt.Fprintf(w, "Warning: unknown crypto key type %d, the key's layout can't be checked\n", cert.CryptoKeyType)
+	}
+}
+
+// exportMode describes a way of writing a key other than the two-line format
+type exportMode struct {
+	suffix      string                       // Default output file suffix
+	description string                       // What is written, for status messages
+	encode      func([]byte) ([]byte, error) // Builds the output from the input key data
+}
+
+var (
+	// reverseExport decodes a two-line key back to the raw binary keypair
+	reverseExport = exportMode{".bin", "Binary keypair", i2pkeys.ToBinary}
+
+	// pubOnlyExport writes just the destination line, safe to share. Its suffix stays clear of
+	// the .pub of redactExport and the .dest of runSplit, so the modes don't overwrite each other.
+	pubOnlyExport = exportMode{".destination", "Public destination", i2pkeys.FormatDestination}
+
+	// singleExport writes the full keypair as one line, without the destination line
+	singleExport = exportMode{".b64", "Single-line keypair", i2pkeys.ToSingleLine}
+
+	// redactExport writes the destination alone to a .pub file, for publishing
+	redactExport = exportMode{".pub", "Public destination (private key removed)", i2pkeys.RedactPrivateKey}
+
+	// repairExport rebuilds the destination line from the full key line
+	repairExport = exportMode{".formatted", "Repaired key", i2pkeys.Reformat}
+
+	// transcodeExport rewrites each Base64 line in the other alphabet, standard or I2P
+	transcodeExport = exportMode{".transcoded", "Transcoded Base64", i2pkeys.TranscodeLines}
+
+	// pemExport wraps the destination and full keypair in PEM blocks
+	pemExport = exportMode{".pem", "PEM key", func(data []byte) ([]byte, error) {
+		keyPair, err := i2pkeys.ParseKeyPair(data)
+		if err != nil {
+			return nil, err
+		}
+		return i2pkeys.ToPEM(keyPair)
+	}}
+)
+
+// runExport writes a key in the given export form and returns the exit code
+func runExport(inputFile, outputFile string, mode exportMode, output outputOptions) int {
+	if outputFile == "" {
+		outputFile = defaultOutputPath(inputFile, mode.suffix)
+	}
+
+	status := output.statusWriter(outputFile)
+
+	data, err := loadInput(inputFile)
+	if err == nil {
+		err = checkInputSize(data, output.force)
+	}
+	if err != nil {
+		return fail(status, err)
+	}
+
+	exported, err := mode.encode(data)
+	if err != nil {
+		return fail(status, err)
+	}
+
+	if err := writeOutput(outputFile, exported, output); err != nil {
+		return fail(status, err)
+	}
+
+	switch {
+	case output.quiet:
+		output.printResult(outputFile)
+	case output.dryRun:
+		fmt.Fprintf(status, "WOULD WRITE %s %s -> %s\n", strings.ToLower(mode.description), inputFile, outputFile)
+	default:
+		fmt.Fprintf(status, "%s written to %s\n", mode.description, outputFile)
+	}
+	return exitOK
+}
+
+// runSplit writes the destination and the private keys of a keypair to separate files, each as
+// one I2P Base64 line, and returns the exit code. The files are named after outputFile, or after
+// the input when it is empty.
+func runSplit(inputFile, outputFile string, output outputOptions) int {
+	destPath, privPath := outputFile+".dest", outputFile+".priv"
+	if outputFile == "" {
+		destPath, privPath = defaultOutputPath(inputFile, ".dest"), defaultOutputPath(inputFile, ".priv")
+	}
+	if outputFile == "-" || destPath == "-" {
+		fmt.Fprintln(os.Stderr, "Error: -split writes two files, give -out the name to write them under")
+		return exitUsage
+	}
+
+	status := output.statusWriter(destPath)
+
+	data, err := loadInput(inputFile)
+	if err == nil {
+		err = checkInputSize(data, output.force)
+	}
+	if err != nil {
+		return fail(status, err)
+	}
+
+	dest, priv, err := i2pkeys.SplitKeyPair(data)
+	if err != nil {
+		return fail(status, err)
+	}
+
+	// Check both outputs before writing either, so a refusal never leaves half a split behind
+	check := output
+	check.dryRun = true
+	for _, path := range []string{destPath, privPath} {
+		if err := writeOutput(path, nil, check); err != nil {
+			return fail(status, err)
+		}
+	}
+	if err := writeOutput(destPath, []byte(i2pkeys.EncodeI2PBase64(dest)+"\n"), output); err != nil {
+		return fail(status, err)
+	}
+	if err := writeOutput(privPath, []byte(i2pkeys.EncodeI2PBase64(priv)+"\n"), output); err != nil {
+		return fail(status, err)
+	}
+
+	switch {
+	case output.quiet:
+		output.printResult(destPath)
+		output.printResult(privPath)
+	case output.dryRun:
+		fmt.Fprintf(status, "WOULD SPLIT %s -> %s, %s\n", inputFile, destPath, privPath)
+	default:
+		fmt.Fprintf(status, "Destination written to %s\n", destPath)
+		fmt.Fprintf(status, "Private keys written to %s\n", privPath)
+	}
+	return exitOK
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
+)
+
+// Exit codes of the CLI. Scripts branch on these, so existing values must not change.
+const (
+	exitOK         = 0 // Success; for check and validate, the key passed
+	exitFailure    = 1 // An error not covered below, an interrupted run, or failures in a directory run
+	exitUsage      = 2 // Missing or invalid arguments, as for flag parsing errors
+	exitIO         = 3 // The input could not be read or the output could not be written
+	exitFormat     = 4 // The input is not a key in a format the tool understands
+	exitValidation = 5 // The key was understood but failed a check
+)
+
+// ioError marks an error reading input or writing output, which exits with exitIO
+type ioError struct {
+	err error
+}
+
+func (e *ioError) Error() string { return e.err.Error() }
+func (e *ioError) Unwrap() error { return e.err }
+
+// exitCode picks the exit code for an error returned while handling a key
+func exitCode(err error) int {
+	var ioErr *ioError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ioErr):
+		return exitIO
+	case errors.Is(err, i2pkeys.ErrAlphabetMismatch),
+		errors.Is(err, i2pkeys.ErrRoundTrip),
+		errors.Is(err, i2pkeys.ErrDestinationMismatch),
+		errors.Is(err, i2pkeys.ErrAddressMismatch),
+		errors.Is(err, i2pkeys.ErrSigningTypeNotAllowed),
+		errors.Is(err, i2pkeys.ErrLengthMismatch):
+		return exitValidation
+	case errors.Is(err, errInputTooLarge),
+		errors.Is(err, i2pkeys.ErrKeyTooShort),
+		errors.Is(err, i2pkeys.ErrInvalidBase64),
+		errors.Is(err, i2pkeys.ErrInvalidHex),
+		errors.Is(err, i2pkeys.ErrInvalidCertificate),
+		errors.Is(err, i2pkeys.ErrInvalidFormat),
+		errors.Is(err, i2pkeys.ErrSwappedLines),
+		errors.Is(err, i2pkeys.ErrInvalidPEM),
+		errors.Is(err, i2pkeys.ErrI2CPSessionConfig),
+		errors.Is(err, i2pkeys.ErrNoPrivateKey),
+		errors.Is(err, i2pkeys.ErrZipArchive),
+		errors.Is(err, i2pkeys.ErrUnsupportedKeyType):
+		return exitFormat
+	default:
+		return exitFailure
+	}
+}
+
+// fail prints err to w and returns its exit code
+func fail(w io.Writer, err error) int {
+	fmt.Fprintf(w, "Error: %s\n", err)
+	return exitCode(err)
+}
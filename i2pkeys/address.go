@@ -0,0 +1,138 @@
+package i2pkeys
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// I2P addresses use lowercase RFC4648 Base32 without padding
+var i2pB32Encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Base32Address computes the .b32.i2p hostname for raw destination bytes. The hash covers the
+// destination and nothing else, so a whole keypair or a cut-off destination is an error rather
+// than the address of some other identity.
+func Base32Address(destination []byte) (string, error) {
+	if err := ValidateDestination(destination); err != nil {
+		return "", fmt.Errorf("not exactly a destination: %w", err)
+	}
+
+	hash := DestinationHash(destination)
+	return toI2PBase32(hash[:]) + base32AddressSuffix, nil
+}
+
+// Suffix of the hostnames made from destination hashes
+const base32AddressSuffix = ".b32.i2p"
+
+// Length of a 32-byte destination hash in unpadded Base32, the part of an address before the suffix
+const base32HashLength = 52
+
+// ParseBase32Address returns the destination hash encoded in a .b32.i2p address, so it can be
+// compared with DestinationHash. The suffix is optional and case is ignored.
+func ParseBase32Address(addr string) ([32]byte, error) {
+	var hash [32]byte
+
+	encoded := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(addr)), base32AddressSuffix)
+	decoded, err := fromI2PBase32(encoded)
+	if err != nil {
+		return hash, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+
+	// Longer names are encrypted leaseset addresses, which don't hold a plain hash
+	if len(decoded) != len(hash) {
+		return hash, fmt.Errorf("%w: decodes to %d bytes, expected %d", ErrInvalidAddress, len(decoded), len(hash))
+	}
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+// VerifyAddress checks that key data, in any form ExtractDestination reads, belongs to the
+// .b32.i2p address addr. A key of any other destination fails with ErrAddressMismatch; for
+// multi-key data only the first key is checked.
+func VerifyAddress(data []byte, addr string) error {
+	expected, err := ParseBase32Address(addr)
+	if err != nil {
+		return err
+	}
+
+	destination, err := ExtractDestination(data)
+	if err != nil {
+		return err
+	}
+	if hash := DestinationHash(destination); hash != expected {
+		return fmt.Errorf("%w: the key is %s%s, expected %s%s", ErrAddressMismatch,
+			toI2PBase32(hash[:]), base32AddressSuffix, toI2PBase32(expected[:]), base32AddressSuffix)
+	}
+	return nil
+}
+
+// DestinationHash returns the SHA-256 hash of the complete destination, certificate included,
+// which identifies the destination on the network
+func DestinationHash(destination []byte) [32]byte {
+	return sha256.Sum256(destination)
+}
+
+// SameDestination reports whether two key files hold the same I2P identity, whatever form
+// each is stored in, by comparing the hashes of their destinations
+func SameDestination(pathA, pathB string) (bool, error) {
+	var hashes [2][32]byte
+	for i, path := range []string{pathA, pathB} {
+		hash, err := fileDestinationHash(path)
+		if err != nil {
+			return false, err
+		}
+		hashes[i] = hash
+	}
+	return hashes[0] == hashes[1], nil
+}
+
+// DedupFiles groups key files by identity, whatever form each is stored in. unique holds the
+// first path of each identity, in the order given; dupes maps each of those that was seen more
+// than once to the later paths holding the same destination. Any unreadable file is an error.
+func DedupFiles(paths []string) (unique []string, dupes map[string][]string, err error) {
+	dupes = make(map[string][]string)
+	first := make(map[[32]byte]string)
+	for _, path := range paths {
+		hash, err := fileDestinationHash(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if original, seen := first[hash]; seen {
+			dupes[original] = append(dupes[original], path)
+			continue
+		}
+		first[hash] = path
+		unique = append(unique, path)
+	}
+	return unique, dupes, nil
+}
+
+// fileDestinationHash reads a key file in any supported form and hashes its destination
+func fileDestinationHash(path string) ([32]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if data, err = DecompressKeyData(data); err != nil {
+		return [32]byte{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	destination, err := ExtractDestination(data)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return DestinationHash(destination), nil
+}
+
+// toI2PBase32 converts binary data to I2P's Base32 variant
+func toI2PBase32(data []byte) string {
+	return i2pB32Encoding.EncodeToString(data)
+}
+
+// fromI2PBase32 converts I2P Base32 format back to binary
+func fromI2PBase32(i2pBase32 string) ([]byte, error) {
+	return i2pB32Encoding.DecodeString(strings.ToLower(i2pBase32))
+}
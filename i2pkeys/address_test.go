@@ -0,0 +1,41 @@
+package i2pkeys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBase32AddressNeedsExactDestination(t *testing.T) {
+	keyPair := testKeyPair(t, 7)
+	destLength, err := certLength(keyPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := Base32Address(keyPair[:destLength])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(address) != base32HashLength+len(base32AddressSuffix) || !strings.HasSuffix(address, base32AddressSuffix) {
+		t.Errorf("unexpected address %q", address)
+	}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{"whole keypair", keyPair, ErrInvalidCertificate},
+		{"destination with a byte extra", keyPair[:destLength+1], ErrInvalidCertificate},
+		{"certificate cut off", keyPair[:destLength-1], ErrInvalidCertificate},
+		{"shorter than a destination", keyPair[:destinationLength-1], ErrKeyTooShort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Base32Address(tt.input); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
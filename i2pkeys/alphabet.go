@@ -0,0 +1,65 @@
+package i2pkeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// I2PAlphabet is the Base64 alphabet of I2P, which uses '-' and '~' instead of '+' and '/'
+const I2PAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~"
+
+// base64Alphabet is an alphabet together with the encoding built from it
+type base64Alphabet struct {
+	chars    string
+	encoding *base64.Encoding
+}
+
+// currentAlphabet is the alphabet key text is read and written in, set by SetAlphabet
+var currentAlphabet atomic.Pointer[base64Alphabet]
+
+func init() {
+	currentAlphabet.Store(&base64Alphabet{I2PAlphabet, base64.NewEncoding(I2PAlphabet)})
+}
+
+// SetAlphabet replaces the Base64 alphabet used for every key line the package reads or writes,
+// for forks of I2P that changed it. The alphabet must be 64 distinct ASCII characters other than
+// '=' and whitespace. An empty string restores I2PAlphabet. The setting is global, so it is
+// meant to be made once at startup. CheckAlphabet and TranscodeLines still recognise
+// standard Base64 by its '+' and '/'.
+func SetAlphabet(alphabet string) error {
+	if alphabet == "" {
+		alphabet = I2PAlphabet
+	}
+	if len(alphabet) != 64 {
+		return fmt.Errorf("alphabet has %d characters, need 64", len(alphabet))
+	}
+	for i, r := range alphabet {
+		if r > 0x7f || r <= ' ' || r == '=' {
+			return fmt.Errorf("alphabet character %q is not allowed", r)
+		}
+		if strings.IndexRune(alphabet, r) != i {
+			return fmt.Errorf("alphabet repeats %q", r)
+		}
+	}
+
+	currentAlphabet.Store(&base64Alphabet{alphabet, base64.NewEncoding(alphabet)})
+	return nil
+}
+
+// EncodeI2PBase64 encodes data as padded I2P Base64 in the current alphabet, the form of each
+// line of a two-line key
+func EncodeI2PBase64(data []byte) string {
+	return toI2PBase64(data)
+}
+
+// i2pEncoding returns the encoding of the current alphabet
+func i2pEncoding() *base64.Encoding {
+	return currentAlphabet.Load().encoding
+}
+
+// isI2PBase64Char reports whether r can appear in I2P Base64 text, padding included
+func isI2PBase64Char(r rune) bool {
+	return r == '=' || strings.ContainsRune(currentAlphabet.Load().chars, r)
+}
@@ -0,0 +1,33 @@
+package i2pkeys
+
+import (
+	"fmt"
+	"os"
+)
+
+// ConvertToBinary converts a two-line formatted key file back to the raw binary keypair
+func ConvertToBinary(inputPath, outputPath string) error {
+	// Read the formatted key file
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	binaryData, err := ToBinary(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputPath, err)
+	}
+
+	return WriteKeyFile(outputPath, binaryData, Options{})
+}
+
+// ToBinary decodes two-line formatted key data back to the raw binary keypair
+func ToBinary(data []byte) ([]byte, error) {
+	data = stripBOM(data)
+	if !IsCorrectFormat(string(data)) {
+		return nil, ErrInvalidFormat
+	}
+
+	// Line 2 holds the full keypair
+	return decodeKeyData(data)
+}
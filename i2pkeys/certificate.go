@@ -0,0 +1,189 @@
+package i2pkeys
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Sizes of the fixed key slots at the start of a destination
+const (
+	publicKeySlotLength  = 256
+	signingKeySlotLength = 128
+)
+
+// Offset of the certificate within a destination, right after the key slots
+const certificateOffset = publicKeySlotLength + signingKeySlotLength
+
+// Size of the certificate header: 1-byte type + 2-byte payload length
+const certificateHeaderLength = 3
+
+// Minimum size in bytes of a destination, reached with a NULL certificate.
+// Its I2P Base64 encoding is the familiar 516 characters.
+const destinationLength = certificateOffset + certificateHeaderLength
+
+// Certificate types defined by the common structures spec
+const (
+	certTypeNull     = 0
+	certTypeHashcash = 1
+	certTypeHidden   = 2
+	certTypeSigned   = 3
+	certTypeMultiple = 4
+	certTypeKey      = 5
+)
+
+// Certificate is the certificate that ends a destination. The key type fields are only
+// set for KEY certificates; every other type implies DSA-SHA1 and ElGamal, whose codes are 0.
+type Certificate struct {
+	Type           byte   `json:"type"`
+	Length         uint16 `json:"length"`
+	Payload        []byte `json:"payload"`
+	SigningKeyType uint16 `json:"signing_key_type"`
+	CryptoKeyType  uint16 `json:"crypto_key_type"`
+}
+
+// ParseCertificate reads the certificate of the destination at the start of destination.
+// Bytes after the declared payload, such as private keys, are ignored.
+func ParseCertificate(destination []byte) (*Certificate, error) {
+	if len(destination) < destinationLength {
+		return nil, fmt.Errorf("%w: %d bytes, need at least %d for a destination", ErrKeyTooShort, len(destination), destinationLength)
+	}
+
+	// The header is the certificate type followed by the big-endian payload length
+	cert := &Certificate{
+		Type:   destination[certificateOffset],
+		Length: binary.BigEndian.Uint16(destination[certificateOffset+1 : destinationLength]),
+	}
+	remaining := len(destination) - destinationLength
+	if int(cert.Length) > remaining {
+		return nil, fmt.Errorf("%w: certificate declares %d bytes of payload but only %d remain", ErrInvalidCertificate, cert.Length, remaining)
+	}
+	cert.Payload = destination[destinationLength : destinationLength+int(cert.Length)]
+
+	// The KEY certificate payload starts with the 2-byte signing and crypto key types
+	if cert.Type == certTypeKey {
+		if len(cert.Payload) < 4 {
+			return nil, fmt.Errorf("%w: KEY certificate payload is too short to hold the key types", ErrInvalidCertificate)
+		}
+		cert.SigningKeyType = binary.BigEndian.Uint16(cert.Payload[0:2])
+		cert.CryptoKeyType = binary.BigEndian.Uint16(cert.Payload[2:4])
+	}
+	return cert, nil
+}
+
+// TypeName returns the spec name of the certificate type
+func (c *Certificate) TypeName() string {
+	return certificateTypeName(c.Type)
+}
+
+// DestinationLength returns the length of the destination the certificate ends
+func (c *Certificate) DestinationLength() int {
+	return destinationLength + int(c.Length)
+}
+
+// certLength returns the full length of the destination at the start of decoded,
+// including the certificate and any extra key data it carries. A KEY certificate too short
+// for the key bytes its types push out of the slots would put the boundary inside the
+// signing key, so it is rejected when both types are known. A destination has exactly one
+// encryption key type; the extra encryption keys an LS2 lease set can advertise belong to
+// the lease set, not to the destination or the key file.
+func certLength(decoded []byte) (int, error) {
+	cert, err := ParseCertificate(decoded)
+	if err != nil {
+		return 0, err
+	}
+
+	if cert.Type == certTypeKey {
+		sigInfo, sigErr := lookupSigningKeyType(cert.SigningKeyType)
+		cryptoInfo, cryptoErr := lookupCryptoKeyType(cert.CryptoKeyType)
+		if needed := keyCertificateLength(sigInfo, cryptoInfo); sigErr == nil && cryptoErr == nil && int(cert.Length) < needed {
+			return 0, fmt.Errorf("%w: %s and %s keys need %d bytes of KEY certificate payload, it declares %d",
+				ErrInvalidCertificate, sigInfo.name, cryptoInfo.name, needed, cert.Length)
+		}
+	}
+	return cert.DestinationLength(), nil
+}
+
+// SigningKeyType reports the signature algorithm declared by a destination's certificate
+func SigningKeyType(destination []byte) (string, error) {
+	sigType, _, err := destinationKeyTypes(destination)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := lookupSigningKeyType(sigType)
+	if err != nil {
+		return "", err
+	}
+	return info.name, nil
+}
+
+// CheckSigningType fails with ErrSigningTypeNotAllowed unless the signing key type declared by
+// a destination's certificate is one of the allowed codes, for refusing keys of weak algorithms
+func CheckSigningType(destination []byte, allowed []uint16) error {
+	sigType, _, err := destinationKeyTypes(destination)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(allowed, sigType) {
+		return nil
+	}
+
+	names := make([]string, len(allowed))
+	for i, code := range allowed {
+		names[i] = signingTypeName(code)
+	}
+	return fmt.Errorf("%w: the key is %s, allowed are %s", ErrSigningTypeNotAllowed, signingTypeName(sigType), strings.Join(names, ", "))
+}
+
+// signingTypeName names a signing key type code, falling back to the number for unknown types
+func signingTypeName(code uint16) string {
+	if info, err := lookupSigningKeyType(code); err == nil {
+		return info.name
+	}
+	return fmt.Sprintf("type %d", code)
+}
+
+// SigningPublicKey returns the signing public key of a destination, sized by the signing key
+// type its certificate declares, for verifying signatures made by the destination
+func SigningPublicKey(destination []byte) ([]byte, error) {
+	layout, err := newKeyFileLayout(destination)
+	if err != nil {
+		return nil, err
+	}
+	return layout.signingPublicKey()
+}
+
+// destinationKeyTypes returns the signing and crypto key type codes declared by a destination
+func destinationKeyTypes(destination []byte) (sigType, cryptoType uint16, err error) {
+	cert, err := ParseCertificate(destination)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch cert.Type {
+	case certTypeNull, certTypeHashcash, certTypeHidden, certTypeSigned, certTypeMultiple:
+		// Only KEY certificates change the key types; the older types all carry the
+		// original DSA-SHA1 and ElGamal keys, whatever their payload holds
+		return sigTypeDSASHA1, cryptoTypeElGamal, nil
+	case certTypeKey:
+		return cert.SigningKeyType, cert.CryptoKeyType, nil
+	default:
+		return 0, 0, fmt.Errorf("%w: unsupported certificate type %d", ErrInvalidCertificate, cert.Type)
+	}
+}
+
+// ValidateDestination checks that a destination's certificate is consistent with its length
+func ValidateDestination(destination []byte) error {
+	cert, err := ParseCertificate(destination)
+	if err != nil {
+		return err
+	}
+
+	// Any bytes after the declared certificate payload do not belong to the destination
+	if cert.DestinationLength() != len(destination) {
+		return fmt.Errorf("%w: certificate declares %d bytes of payload but %d remain", ErrInvalidCertificate, cert.Length, len(destination)-destinationLength)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package i2pkeys
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// debugLogger receives the decisions made while decoding and formatting keys, nil when off
+var debugLogger atomic.Pointer[log.Logger]
+
+// SetDebugLogger sends a step-by-step account of how key data is detected and split to l,
+// such as which encoding was recognised and where the destination ends. Nil turns it off.
+func SetDebugLogger(l *log.Logger) {
+	debugLogger.Store(l)
+}
+
+// debugf logs one decision when a debug logger is set
+func debugf(format string, args ...any) {
+	if l := debugLogger.Load(); l != nil {
+		l.Printf(format, args...)
+	}
+}
@@ -0,0 +1,58 @@
+package i2pkeys
+
+import "fmt"
+
+// Destination is the raw bytes of an I2P destination: the public keys and certificate that
+// identify a service on the network. It holds nothing secret, so it is safe to log.
+type Destination []byte
+
+// Destination returns the destination of the key pair
+func (k *KeyPair) Destination() Destination {
+	return Destination(k.PublicKey)
+}
+
+// Base32Address computes the .b32.i2p hostname of the destination
+func (d Destination) Base32Address() (string, error) {
+	return Base32Address(d)
+}
+
+// String summarises the destination as its .b32.i2p address and signing key type,
+// for example "xyz...abc.b32.i2p (Ed25519-SHA512)"
+func (d Destination) String() string {
+	address, err := d.Base32Address()
+	if err != nil {
+		return fmt.Sprintf("invalid destination (%d bytes)", len(d))
+	}
+
+	// An unknown key type still has an address worth showing
+	sigType, err := SigningKeyType(d)
+	if err != nil {
+		sigType = "unknown signing key type"
+	}
+	return fmt.Sprintf("%s (%s)", address, sigType)
+}
+
+// String summarises the key pair by its destination and whether private keys are present.
+// It never includes key material, so a KeyPair can be logged with %v or %s. The value
+// receiver makes this hold for both KeyPair and *KeyPair.
+func (k KeyPair) String() string {
+	summary := k.Destination().String()
+	if len(k.PrivateKey) > 0 {
+		return summary + " [private key present]"
+	}
+	return summary + " [no private key]"
+}
+
+// GoString keeps %#v from printing the private key bytes field by field
+func (k KeyPair) GoString() string {
+	return fmt.Sprintf("i2pkeys.KeyPair{%s}", k.String())
+}
+
+// Equal reports whether two key pairs are the same identity, by the hashes of their
+// destinations. Private keys are not compared, so a keypair equals its public-only export.
+func (k *KeyPair) Equal(other *KeyPair) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
+	return DestinationHash(k.PublicKey) == DestinationHash(other.PublicKey)
+}
@@ -0,0 +1,257 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FileStatus is the outcome of converting one file in ConvertDir
+type FileStatus string
+
+// Outcomes of a FileResult
+const (
+	StatusConverted    FileStatus = "converted"     // The output was written
+	StatusWouldConvert FileStatus = "would_convert" // DryRun was set and the output would have been written
+	StatusSkipped      FileStatus = "skipped"       // The file is already in the correct format
+	StatusUpToDate     FileStatus = "up_to_date"    // SkipExisting was set and the output is at least as new
+	StatusLinkSkipped  FileStatus = "link_skipped"  // A symbolic link that was not followed
+	StatusNotKey       FileStatus = "not_a_key"     // A zip entry that doesn't hold a key, see Err; from ConvertZip only
+	StatusFailed       FileStatus = "failed"        // The file could not be converted, see Err
+)
+
+// FileResult is the outcome for one file of a directory conversion
+type FileResult struct {
+	Path   string
+	Status FileStatus
+	Output string // The output path, for converted and up-to-date files
+	Reason string // Why a file was skipped
+	Err    error  // Why the file failed or isn't a key
+}
+
+// DirReport tallies a directory conversion. Files holds every result, sorted by path.
+type DirReport struct {
+	Converted    int // Includes files a dry run would convert
+	Skipped      int // Already in the correct format
+	UpToDate     int
+	LinksSkipped int
+	NotKeys      int // Zip entries left out because they aren't keys
+	Failed       int
+	Files        []FileResult
+}
+
+// DirOptions controls ConvertDir. The zero value converts the files directly in the directory,
+// one at a time, to <file>.formatted and never replaces an existing output.
+type DirOptions struct {
+	Options             // How each output file is written
+	Walk    WalkOptions // Which files are converted
+	Jobs    int         // Number of files converted at once
+
+	Force        bool // Replace existing output files
+	DryRun       bool // Report what would be converted without writing anything
+	SkipExisting bool // Leave inputs whose output is at least as new alone, and replace older outputs
+
+	// OutputPath names the output of an input file; outputs must stay inside the directory
+	OutputPath func(inputPath string) string
+
+	// Progress, when set, receives each result as soon as it is known, one call at a time
+	Progress func(FileResult)
+}
+
+// ConvertDir converts every key file in dir that is not already in the correct format and
+// reports on each. It stops between files once ctx is done, returning the results so far
+// with ctx's error. Other errors mean dir itself could not be read.
+func ConvertDir(ctx context.Context, dir string, opts DirOptions) (*DirReport, error) {
+	run := &dirRun{root: dir, opts: opts, report: &DirReport{}}
+
+	paths := make(chan string)
+	var workers sync.WaitGroup
+	for range max(opts.Jobs, 1) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for path := range paths {
+				run.convertFile(ctx, path)
+			}
+		}()
+	}
+
+	err := WalkKeyFiles(ctx, dir, opts.Walk, func(path string, err error) {
+		if errors.Is(err, ErrLinkSkipped) {
+			run.record(FileResult{Path: path, Status: StatusLinkSkipped, Reason: err.Error()})
+			return
+		}
+		if err != nil {
+			// Report unreadable entries and keep going
+			run.record(FileResult{Path: path, Status: StatusFailed, Err: err})
+			return
+		}
+
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+		}
+	})
+
+	close(paths)
+	workers.Wait()
+
+	slices.SortFunc(run.report.Files, func(a, b FileResult) int { return strings.Compare(a.Path, b.Path) })
+	return run.report, err
+}
+
+// add counts a result under its status and appends it to Files
+func (d *DirReport) add(result FileResult) {
+	switch result.Status {
+	case StatusConverted, StatusWouldConvert:
+		d.Converted++
+	case StatusSkipped:
+		d.Skipped++
+	case StatusUpToDate:
+		d.UpToDate++
+	case StatusLinkSkipped:
+		d.LinksSkipped++
+	case StatusNotKey:
+		d.NotKeys++
+	default:
+		d.Failed++
+	}
+	d.Files = append(d.Files, result)
+}
+
+// dirRun is the state of one ConvertDir call, shared by its workers
+type dirRun struct {
+	root   string // Directory the outputs must stay inside
+	opts   DirOptions
+	mu     sync.Mutex
+	report *DirReport
+
+	copyFormatted bool // Write files that are already in the correct format too, as WatchDir does
+}
+
+// record adds a result to the report and passes it on to the Progress callback
+func (r *dirRun) record(result FileResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.add(result)
+	if r.opts.Progress != nil {
+		r.opts.Progress(result)
+	}
+}
+
+// outputPath names the output of a file, by default beside it with .formatted appended
+func (r *dirRun) outputPath(path string) string {
+	if r.opts.OutputPath == nil {
+		return path + ".formatted"
+	}
+	return r.opts.OutputPath(path)
+}
+
+// convertFile converts a single file and records the outcome
+func (r *dirRun) convertFile(ctx context.Context, path string) {
+	result := r.convert(ctx, path)
+	if result.Status == "" {
+		// Cancelled before anything was written
+		return
+	}
+	r.record(result)
+}
+
+// convert converts one file. The result has no status when ctx was done before writing.
+func (r *dirRun) convert(ctx context.Context, path string) FileResult {
+	failed := func(err error) FileResult {
+		return FileResult{Path: path, Status: StatusFailed, Err: err}
+	}
+	outputPath := r.outputPath(path)
+	force := r.opts.Force
+
+	// Like make, an output at least as new as its input doesn't need converting again
+	if r.opts.SkipExisting {
+		fresh, err := outputUpToDate(path, outputPath)
+		if err != nil {
+			return failed(err)
+		}
+		if fresh {
+			return FileResult{Path: path, Status: StatusUpToDate, Output: outputPath}
+		}
+		force = true
+	}
+
+	data, err := ReadKeyFileContext(ctx, path)
+	if ctx.Err() != nil {
+		return FileResult{}
+	}
+	if err != nil {
+		return failed(err)
+	}
+
+	resultData, err := ConvertKeysAs(data, r.opts.InputFormat)
+	if err == nil && r.opts.Validate {
+		err = ValidateConverted(resultData)
+	}
+	if err != nil {
+		return failed(err)
+	}
+
+	// Leave files that are already formatted alone, unless their line endings or blank lines need fixing
+	if !r.copyFormatted && IsCorrectFormat(string(data)) && bytes.Equal(resultData, data) {
+		return FileResult{Path: path, Status: StatusSkipped, Reason: "already in the correct format"}
+	}
+
+	// Don't start writing once the run has been cancelled
+	if ctx.Err() != nil {
+		return FileResult{}
+	}
+
+	if err := checkDirOutput(r.root, outputPath); err != nil {
+		return failed(err)
+	}
+	if !force {
+		if _, err := os.Lstat(outputPath); err == nil {
+			return failed(fmt.Errorf("%w: %s", ErrOutputExists, outputPath))
+		}
+	}
+
+	if r.opts.DryRun {
+		return FileResult{Path: path, Status: StatusWouldConvert, Output: outputPath}
+	}
+	if err := WriteKeyFile(outputPath, resultData, r.opts.Options); err != nil {
+		return failed(err)
+	}
+	return FileResult{Path: path, Status: StatusConverted, Output: outputPath}
+}
+
+// outputUpToDate reports whether outputPath exists and was modified no earlier than inputPath
+func outputUpToDate(inputPath, outputPath string) (bool, error) {
+	out, err := os.Lstat(outputPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	in, err := os.Stat(inputPath)
+	if err != nil {
+		return false, err
+	}
+	return out.Mode().IsRegular() && !out.ModTime().Before(in.ModTime()), nil
+}
+
+// checkDirOutput refuses output paths that would escape root, directly or through a symbolic link
+func checkDirOutput(root, outputPath string) error {
+	if !withinDir(root, outputPath) {
+		return fmt.Errorf("output path '%s' is outside '%s'", outputPath, root)
+	}
+	if info, err := os.Lstat(outputPath); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("output path '%s' is a symbolic link, refusing to write through it", outputPath)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package i2pkeys
+
+import "errors"
+
+// Sentinel errors returned (wrapped) by the package, for use with errors.Is
+var (
+	// ErrKeyTooShort means the data is too short to hold a destination
+	ErrKeyTooShort = errors.New("key data too short")
+
+	// ErrInvalidBase64 means the data could not be decoded as I2P Base64
+	ErrInvalidBase64 = errors.New("invalid I2P Base64")
+
+	// ErrInvalidHex means input declared as hex could not be decoded
+	ErrInvalidHex = errors.New("invalid hex key data")
+
+	// ErrAlphabetMismatch means the data is Base64 in the standard alphabet instead of I2P's
+	ErrAlphabetMismatch = errors.New("standard Base64 alphabet used instead of I2P Base64")
+
+	// ErrInvalidCertificate means the destination's certificate is malformed
+	ErrInvalidCertificate = errors.New("invalid certificate")
+
+	// ErrInvalidFormat means the data is not in the expected two-line format
+	ErrInvalidFormat = errors.New("key data is not in the two-line format")
+
+	// ErrRoundTrip means formatted output did not decode back to the original key bytes
+	ErrRoundTrip = errors.New("formatted key does not round-trip to the original data")
+
+	// ErrInvalidPEM means PEM input holds no usable I2P key block
+	ErrInvalidPEM = errors.New("invalid I2P PEM data")
+
+	// ErrNoOfflineSignature means the key file holds its signing private key rather than an offline signature block
+	ErrNoOfflineSignature = errors.New("no offline signature block")
+
+	// ErrInvalidAddress means a string is not a well-formed .b32.i2p address
+	ErrInvalidAddress = errors.New("invalid .b32.i2p address")
+
+	// ErrI2CPSessionConfig means the data is an I2CP session message, which ParseI2CPSessionKeys reads
+	ErrI2CPSessionConfig = errors.New("looks like an I2CP SessionConfig, unsupported as a key file")
+
+	// ErrNoPrivateKey means the data is a destination alone, with no private keys to write a keypair file from
+	ErrNoPrivateKey = errors.New("no private key present, cannot produce keypair file")
+
+	// ErrAddressMismatch means a key belongs to another destination than the address it was checked against
+	ErrAddressMismatch = errors.New("key does not match the expected address")
+
+	// ErrSigningTypeNotAllowed means a key's signing key type is not in the allowlist it was checked against
+	ErrSigningTypeNotAllowed = errors.New("signing key type not allowed")
+
+	// ErrLengthMismatch means the key data is not the size its certificate's key types call for
+	ErrLengthMismatch = errors.New("key length does not match its key types")
+
+	// ErrDestinationMismatch means line 1 of two-line data is not the destination at the start of line 2
+	ErrDestinationMismatch = errors.New("destination line is not a prefix of the full key line")
+
+	// ErrSwappedLines means two-line data has the full keypair on line 1 and its destination on line 2
+	ErrSwappedLines = errors.New("key lines are swapped, the full key line comes first")
+
+	// ErrLinkSkipped means WalkKeyFiles found a symbolic link it was not allowed to follow
+	ErrLinkSkipped = errors.New("symbolic link not followed")
+
+	// ErrOutputExists means ConvertDir found an output file already there and Force was not set
+	ErrOutputExists = errors.New("output file already exists")
+
+	// ErrZipArchive means the data is a zip archive, which ConvertZip reads entry by entry
+	ErrZipArchive = errors.New("input is a zip archive, not a key file")
+
+	// ErrUnsupportedKeyType means the certificate declares a key type this package does not know
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
@@ -2,111 +2,241 @@
 package i2pkeys
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
-// I2P uses a custom Base64 encoding with '-' and '~' instead of '+' and '/'
-var i2pB64Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~")
-
 // KeyPair represents an I2P key pair with both public and private components
 type KeyPair struct {
 	PublicKey  []byte // The destination (public key)
 	PrivateKey []byte // The private key
 	FullData   []byte // The complete key data
+	Hostname   string // Alias from a hosts-style "name=base64" line, if any
+
+	// Individual keys, sized by the destination's certificate.
+	// The private keys are nil when the data does not hold them.
+	EncryptionPublicKey  []byte
+	SigningPublicKey     []byte
+	EncryptionPrivateKey []byte
+	SigningPrivateKey    []byte
 }
 
 // ConvertKeyFile converts an I2P binary key file to the two-line format required by Go I2P
 func ConvertKeyFile(inputPath, outputPath string) error {
-	// Read the key file as binary data
-	data, err := os.ReadFile(inputPath)
+	return ConvertKeyFileContext(context.Background(), inputPath, outputPath)
+}
+
+// ConvertKeyFileContext is ConvertKeyFile with cancellation. A read blocked on a slow
+// filesystem is abandoned when ctx is done, and nothing is written after cancellation.
+func ConvertKeyFileContext(ctx context.Context, inputPath, outputPath string) error {
+	return ConvertKeyFileWithOptions(ctx, inputPath, outputPath, Options{})
+}
+
+// ReadKeyFileContext reads a key file, decompressing it when it is gzipped, and returns early
+// with ctx's error when ctx is done first, so a hung read doesn't hold up a cancelled run
+func ReadKeyFileContext(ctx context.Context, path string) ([]byte, error) {
+	data, err := readFileContext(ctx, path)
 	if err != nil {
-		return fmt.Errorf("failed to read key file: %w", err)
+		return nil, err
+	}
+	return DecompressKeyData(data)
+}
+
+// readFileContext reads a file, returning early with ctx's error when ctx is done first
+func readFileContext(ctx context.Context, path string) ([]byte, error) {
+	type readResult struct {
+		data []byte
+		err  error
+	}
+
+	// The read itself can't be interrupted, so run it where it can be abandoned
+	done := make(chan readResult, 1)
+	go func() {
+		data, err := os.ReadFile(path)
+		done <- readResult{data, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-done:
+		return result.data, result.err
 	}
+}
+
+// ConvertKeys converts binary or Base64 key data to the two-line format required by Go I2P
+func ConvertKeys(data []byte) ([]byte, error) {
+	data = stripBOM(data)
 
 	// Check if input is already in the expected format
 	if IsCorrectFormat(string(data)) {
-		// Create output directory if needed
-		outputDir := filepath.Dir(outputPath)
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
-		}
+		debugf("input is already in the two-line format")
+		return canonicalFormat(data), nil
+	}
 
-		// Just copy the file as is
-		if err := os.WriteFile(outputPath, data, 0600); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
-		}
+	// Decode the key from I2P Base64, falling back to raw binary
+	fullKey, err := decodeKeyData(data)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
+	// The certificate determines how much of the key is the destination
+	formattedOutput, err := formatKeyPair(fullKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract public key portion: %w", err)
 	}
 
-	// Try to extract public key information if it's in I2P Base64 format
-	keyData := string(data)
-	var formattedOutput string
-
-	// If data is in I2P Base64 format, try to extract the public key portion
-	if isI2PBase64Format(keyData) {
-		// Split by newlines in case there are multiple keys
-		lines := strings.Split(keyData, "\n")
-		completeKey := lines[0]
-
-		// For I2P tunnel keys, the public key is the first 516 characters
-		// This is a heuristic based on the standard format of I2P keys
-		if len(completeKey) >= 516 {
-			publicPart := completeKey[:516]
-			formattedOutput = publicPart + "\n" + completeKey
-		} else {
-			// If we can't extract, convert the entire binary file
-			completeKey = toI2PBase64(data)
-
-			// Public key is typically the first 516 characters
-			if len(completeKey) >= 516 {
-				publicPart := completeKey[:516]
-				formattedOutput = publicPart + "\n" + completeKey
-			} else {
-				return errors.New("key data too short to extract public key portion")
-			}
+	return []byte(formattedOutput), nil
+}
+
+// IsCorrectFormat checks if the data is already in the correct two-line format
+func IsCorrectFormat(data string) bool {
+	return IsCorrectFormatBytes([]byte(data))
+}
+
+// IsCorrectFormatBytes is IsCorrectFormat for a byte slice, which saves copying data read
+// from a file into a string
+func IsCorrectFormatBytes(data []byte) bool {
+	lines, ok := formattedLines(data)
+	if !ok {
+		return false
+	}
+
+	// Go I2P expects padded lines, so unpadded ones still need converting
+	if len(lines[0])%4 != 0 || len(lines[1])%4 != 0 {
+		return false
+	}
+
+	// The full key line is the longer one, so its buffer fits either
+	buf := make([]byte, i2pEncoding().DecodedLen(len(lines[1])))
+	for _, line := range lines {
+		// The decoder skips carriage returns, which a key line mustn't hold
+		if bytes.IndexByte(line, '\r') >= 0 {
+			return false
 		}
-	} else {
-		// Not in Base64 format, treat as binary and convert
-		completeKey := toI2PBase64(data)
-
-		// Public key is typically the first 516 characters
-		if len(completeKey) >= 516 {
-			publicPart := completeKey[:516]
-			formattedOutput = publicPart + "\n" + completeKey
-		} else {
-			return errors.New("key data too short to extract public key portion")
+		if _, err := i2pEncoding().Decode(buf, line); err != nil {
+			return false
 		}
 	}
+	return true
+}
 
-	// Create output directory if needed
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// isTwoLine reports whether data is two lines of I2P Base64, padded or not
+func isTwoLine(data string) bool {
+	// Rule out most other input before decoding anything
+	if !looksFormatted([]byte(data)) {
+		return false
 	}
 
-	// Write formatted output to file
-	if err := os.WriteFile(outputPath, []byte(formattedOutput), 0600); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	lines := keyLines(data)
+	if len(lines) != 2 {
+		return false
+	}
+
+	// Check if both lines appear to be valid I2P Base64
+	return isI2PBase64Format(lines[0]) && isI2PBase64Format(lines[1])
+}
+
+// IsCorrectFormatStrict is IsCorrectFormat that also decodes both lines and requires line 1
+// to be exactly the destination at the start of line 2. A file whose lines come from
+// different keys passes IsCorrectFormat but would load the wrong identity.
+func IsCorrectFormatStrict(data string) bool {
+	if !IsCorrectFormat(data) {
+		return false
+	}
+	destination, fullKey, err := decodeKeyLines(data)
+	return err == nil && checkDestinationPrefix(destination, fullKey) == nil
+}
+
+// decodeKeyLines decodes the destination and full key lines of two-line key data
+func decodeKeyLines(data string) (destination, fullKey []byte, err error) {
+	lines := keyLines(data)
+	destination, err = fromI2PBase64(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: destination line: %v", ErrInvalidBase64, err)
 	}
+	fullKey, err = fromI2PBase64(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: full key line: %v", ErrInvalidBase64, err)
+	}
+	return destination, fullKey, nil
+}
 
+// checkDestinationPrefix requires destination to be the leading bytes of fullKey, with a private key after it
+func checkDestinationPrefix(destination, fullKey []byte) error {
+	if len(destination) >= len(fullKey) || !bytes.HasPrefix(fullKey, destination) {
+		return ErrDestinationMismatch
+	}
 	return nil
 }
 
-// IsCorrectFormat checks if the data is already in the correct two-line format
-func IsCorrectFormat(data string) bool {
-	lines := strings.Split(strings.TrimSpace(data), "\n")
-	if len(lines) != 2 {
+// LinesSwapped reports whether two-line key data has its lines in reverse order, line 2 being
+// the destination at the start of line 1. Lines of the same length are never swapped, as a
+// destination is always shorter than the keypair it begins.
+func LinesSwapped(data []byte) bool {
+	lines := keyLines(string(stripBOM(data)))
+	if len(lines) != 2 || len(lines[1]) >= len(lines[0]) {
 		return false
 	}
 
-	// Check if both lines appear to be valid I2P Base64
-	return isI2PBase64Format(lines[0]) && isI2PBase64Format(lines[1])
+	fullKey, err := fromI2PBase64(lines[0])
+	if err != nil {
+		return false
+	}
+	destination, err := fromI2PBase64(lines[1])
+	return err == nil && checkDestinationPrefix(destination, fullKey) == nil
+}
+
+// Length of the shortest possible destination line, a destination with a NULL certificate
+const minDestinationLineLength = (destinationLength + 2) / 3 * 4
+
+// looksFormatted is a cheap check that data could be in the two-line format: exactly two
+// non-empty lines, the first long enough for a destination and the second at least as long
+func looksFormatted(data []byte) bool {
+	_, ok := formattedLines(data)
+	return ok
+}
+
+// formattedLines returns the two lines of data, trimmed, when looksFormatted accepts it
+func formattedLines(data []byte) (lines [2][]byte, ok bool) {
+	n := 0
+	for line := range bytes.SplitSeq(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if n == 2 {
+				return lines, false
+			}
+			lines[n] = line
+			n++
+		}
+	}
+	return lines, n == 2 && len(lines[0]) >= minDestinationLineLength && len(lines[1]) >= len(lines[0])
+}
+
+// ValidateFormat checks the two-line format, that line 1 is a well-formed destination and
+// that it is the start of line 2
+func ValidateFormat(data []byte) error {
+	if !IsCorrectFormat(string(data)) {
+		if LinesSwapped(data) {
+			return ErrSwappedLines
+		}
+		return ErrInvalidFormat
+	}
+
+	destination, fullKey, err := decodeKeyLines(string(data))
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateDestination(destination); err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+
+	return checkDestinationPrefix(destination, fullKey)
 }
 
 // isI2PBase64Format checks if a string appears to be in I2P Base64 format
@@ -119,10 +249,7 @@ func isI2PBase64Format(data string) bool {
 
 	// Check for I2P Base64 character set
 	for _, r := range data {
-		if !((r >= 'A' && r <= 'Z') ||
-			(r >= 'a' && r <= 'z') ||
-			(r >= '0' && r <= '9') ||
-			r == '-' || r == '~' || r == '=') {
+		if !isI2PBase64Char(r) {
 			return false
 		}
 	}
@@ -132,14 +259,32 @@ func isI2PBase64Format(data string) bool {
 	return err == nil
 }
 
+// CheckAlphabet reports ErrAlphabetMismatch when key text looks like standard Base64
+// rather than I2P Base64. The alphabets only differ in '+' and '/' versus '-' and '~',
+// so text made only of the shared characters decodes to the same bytes under both.
+func CheckAlphabet(data string) error {
+	for i, line := range keyLines(data) {
+		if !strings.ContainsAny(line, "+/") {
+			continue
+		}
+
+		// Valid under the standard alphabet but not under I2P's
+		if _, err := base64.StdEncoding.DecodeString(line); err == nil && !isI2PBase64Format(line) {
+			return fmt.Errorf("%w: line %d uses the standard Base64 alphabet ('+' and '/')", ErrAlphabetMismatch, i+1)
+		}
+	}
+	return nil
+}
+
 // toI2PBase64 converts binary data to I2P's Base64 variant
 func toI2PBase64(data []byte) string {
-	return i2pB64Encoding.EncodeToString(data)
+	return i2pEncoding().EncodeToString(data)
 }
 
-// fromI2PBase64 converts I2P Base64 format back to binary
+// fromI2PBase64 converts I2P Base64 format back to binary. The '=' padding is optional,
+// as keys are often copied without it.
 func fromI2PBase64(i2pBase64 string) ([]byte, error) {
-	return i2pB64Encoding.DecodeString(i2pBase64)
+	return decodeBase64(i2pEncoding(), i2pBase64)
 }
 
 // FormatKeysFile formats an existing I2P Base64 key into the proper two-line format
@@ -150,15 +295,24 @@ func FormatKeysFile(inputPath, outputPath string) error {
 		return fmt.Errorf("failed to read key file: %w", err)
 	}
 
+	formattedOutput, err := FormatKeys(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputPath, err)
+	}
+
+	// Already in the correct format and formatting in place, nothing to write
+	if inputPath == outputPath && bytes.Equal(formattedOutput, data) {
+		return nil
+	}
+
+	return WriteKeyFile(outputPath, formattedOutput, Options{})
+}
+
+// FormatKeys formats existing I2P Base64 key data into the proper two-line format
+func FormatKeys(data []byte) ([]byte, error) {
 	// Check if it's already in the correct format
 	if IsCorrectFormat(string(data)) {
-		// Already in the correct format, just copy
-		if inputPath != outputPath {
-			if err := os.WriteFile(outputPath, data, 0600); err != nil {
-				return fmt.Errorf("failed to write output file: %w", err)
-			}
-		}
-		return nil
+		return canonicalFormat(data), nil
 	}
 
 	// Clean the input
@@ -167,56 +321,150 @@ func FormatKeysFile(inputPath, outputPath string) error {
 	// Split by lines (there might be multiple keys)
 	lines := strings.Split(cleanedInput, "\n")
 
-	// Process the first non-empty line
+	// Process the first non-empty line, or the line after it when the first is only its
+	// destination, as in formatted output written out more than once
 	var completeKey string
-	for _, line := range lines {
+	for i, line := range lines {
 		if strings.TrimSpace(line) != "" {
 			completeKey = line
+			if i+1 < len(lines) && isDestinationOf(line, lines[i+1]) {
+				completeKey = lines[i+1]
+			}
 			break
 		}
 	}
 
-	// Ensure we have enough data
-	if len(completeKey) < 516 {
-		return errors.New("key data too short to format correctly")
+	// Decode the key so the certificate can be read
+	fullKey, err := fromI2PBase64(completeKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBase64, err)
 	}
 
-	// Extract public key (first 516 characters)
-	publicPart := completeKey[:516]
-
 	// Create the proper two-line format
-	formattedOutput := publicPart + "\n" + completeKey
+	formattedOutput, err := formatKeyPair(fullKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format key: %w", err)
+	}
 
-	// Create output directory if needed
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	return []byte(formattedOutput), nil
+}
+
+// Reformat rebuilds the two-line format from the full keypair on line 2, discarding whatever
+// line 1 holds. It repairs keys whose destination line was truncated or edited by hand, and
+// puts the lines of a key back in order when LinesSwapped finds them reversed.
+func Reformat(data []byte) ([]byte, error) {
+	lines := keyLines(string(data))
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("%w: expected 2 lines, found %d", ErrInvalidFormat, len(lines))
 	}
 
-	// Write to output file
-	if err := os.WriteFile(outputPath, []byte(formattedOutput), 0600); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	fullKeyLine := lines[1]
+	if LinesSwapped(data) {
+		debugf("lines are swapped, reading the full key from line 1")
+		fullKeyLine = lines[0]
 	}
 
-	return nil
+	fullKey, err := fromI2PBase64(strings.TrimSpace(fullKeyLine))
+	if err != nil {
+		return nil, fmt.Errorf("%w: full key line: %v", ErrInvalidBase64, err)
+	}
+
+	// The destination is re-derived from the certificate inside the full key
+	formattedOutput, err := formatKeyPair(fullKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild destination line: %w", err)
+	}
+
+	return []byte(formattedOutput), nil
+}
+
+// canonicalFormat rewrites two-line key text with exactly one LF between the lines,
+// keeping a trailing newline if the text had one
+func canonicalFormat(data []byte) []byte {
+	lines := keyLines(string(data))
+	formatted := strings.Join(lines, "\n")
+	if bytes.HasSuffix(data, []byte("\n")) {
+		formatted += "\n"
+	}
+	return []byte(formatted)
 }
 
 // cleanI2PBase64 cleans a string to ensure it only contains valid I2P Base64 characters
 func cleanI2PBase64(data string) string {
-	// Remove whitespace
-	data = strings.TrimSpace(data)
+	// Remove a byte order mark and whitespace
+	data = strings.TrimSpace(strings.TrimPrefix(data, utf8BOM))
 
 	// Clean the line of any invalid characters
 	var cleaned strings.Builder
 	for _, r := range data {
-		if (r >= 'A' && r <= 'Z') ||
-			(r >= 'a' && r <= 'z') ||
-			(r >= '0' && r <= '9') ||
-			r == '-' || r == '~' || r == '=' ||
-			r == '\n' {
+		if isI2PBase64Char(r) || r == '\n' {
 			cleaned.WriteRune(r)
 		}
 	}
 
-	return cleaned.String()
+	// Join a key wrapped at a fixed width so it isn't split into several keys
+	unwrapped, _ := unwrapBase64(cleaned.String())
+	return unwrapped
+}
+
+// unwrapBase64 joins the lines of Base64 wrapped at a fixed width, as PEM (64 columns) and
+// MIME (76) exports do: a run of lines all as long as the first, ending with one no longer.
+// Wrapped lines are always shorter than a destination line, so two-line and one-key-per-line
+// text is left as it is. Keys wrapped back to back need a blank line between them when a
+// key's length is a multiple of the width. The result reports whether anything was joined.
+func unwrapBase64(data string) (string, bool) {
+	lines := strings.Split(data, "\n")
+	out := make([]string, 0, len(lines))
+	joined := false
+	for i := 0; i < len(lines); {
+		width := len(strings.TrimSpace(lines[i]))
+		end := i + 1
+		if width%4 == 0 && width >= 16 && width < minDestinationLineLength {
+			for end < len(lines) && wrappedLine(lines[end], width) == width {
+				end++
+			}
+			if end < len(lines) && wrappedLine(lines[end], width) > 0 {
+				end++
+			}
+		}
+
+		// A lone short line, or a run with anything but Base64 in it, is kept as it is
+		if end-i < 2 || wrappedLine(lines[i], width) != width {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+
+		var key strings.Builder
+		for _, line := range lines[i:end] {
+			key.WriteString(strings.TrimSpace(line))
+		}
+		out = append(out, key.String())
+		joined = true
+		i = end
+	}
+	return strings.Join(out, "\n"), joined
+}
+
+// wrappedLine returns the length of line when it could be part of I2P Base64 wrapped at width, otherwise 0
+func wrappedLine(line string, width int) int {
+	line = strings.TrimSpace(line)
+	if len(line) > width || strings.ContainsFunc(line, func(r rune) bool { return !isI2PBase64Char(r) }) {
+		return 0
+	}
+	return len(line)
+}
+
+// utf8BOM is the byte order mark some editors, notably on Windows, write at the start of text files
+const utf8BOM = "\uFEFF"
+
+// stripBOM removes a leading UTF-8 byte order mark from text input. Binary keys start with
+// arbitrary bytes, so the mark is only dropped when the rest of the data is valid UTF-8.
+func stripBOM(data []byte) []byte {
+	rest, ok := bytes.CutPrefix(data, []byte(utf8BOM))
+	if !ok || !utf8.Valid(rest) {
+		return data
+	}
+	debugf("dropped a UTF-8 byte order mark")
+	return rest
 }
@@ -0,0 +1,286 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Signing key types GenerateTestKeyPair is tested with: DSA-SHA1, ECDSA-SHA256-P256 and Ed25519
+var testSigTypes = []int{0, 1, 7}
+
+// testKeyPair returns a binary keypair from GenerateTestKeyPair, failing the test on error
+func testKeyPair(t testing.TB, sigType int) []byte {
+	t.Helper()
+	keyPair, err := GenerateTestKeyPair(sigType)
+	if err != nil {
+		t.Fatalf("GenerateTestKeyPair(%d): %v", sigType, err)
+	}
+	return keyPair
+}
+
+// checkTwoLine fails the test unless formatted is keyPair in the two-line format
+func checkTwoLine(t *testing.T, formatted, keyPair []byte) {
+	t.Helper()
+	if !IsCorrectFormat(string(formatted)) {
+		t.Fatalf("output is not in the two-line format:\n%s", formatted)
+	}
+	lines := keyLines(string(formatted))
+	if len(lines) != 2 {
+		t.Fatalf("output has %d lines, want 2", len(lines))
+	}
+	destination, err := fromI2PBase64(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullKey, err := fromI2PBase64(lines[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fullKey, keyPair) {
+		t.Error("full key line does not decode to the input keypair")
+	}
+	destLength, err := certLength(keyPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(destination, keyPair[:destLength]) {
+		t.Errorf("destination line is not the first %d bytes of the keypair", destLength)
+	}
+}
+
+func TestConvertKeysRoundTrip(t *testing.T) {
+	for _, sigType := range testSigTypes {
+		keyPair := testKeyPair(t, sigType)
+		formatted, err := ConvertKeys(keyPair)
+		if err != nil {
+			t.Fatalf("sig type %d: %v", sigType, err)
+		}
+
+		tests := []struct {
+			name  string
+			input []byte
+		}{
+			{"binary", keyPair},
+			{"single line", []byte(toI2PBase64(keyPair) + "\n")},
+			{"already formatted", formatted},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("sigtype %d %s", sigType, tt.name), func(t *testing.T) {
+				got, err := ConvertKeys(tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkTwoLine(t, got, keyPair)
+				if !bytes.Equal(got, formatted) {
+					t.Errorf("got\n%s\nwant\n%s", got, formatted)
+				}
+			})
+		}
+	}
+}
+
+// Converting a converted key again must leave it as it is, for every signing key type
+func TestConvertKeysIdempotent(t *testing.T) {
+	for sigType, name := range SupportedSigningTypes() {
+		t.Run(name, func(t *testing.T) {
+			once, err := ConvertKeys(testKeyPair(t, int(sigType)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			twice, err := ConvertKeys(once)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(twice, once) {
+				t.Errorf("converting again changed the key:\n%s\nwant\n%s", twice, once)
+			}
+		})
+	}
+}
+
+func TestConvertKeysTooShort(t *testing.T) {
+	keyPair := testKeyPair(t, 7)
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"binary", keyPair[:100]},
+		{"destination cut short", keyPair[:destinationLength-1]},
+		{"short Base64 line", []byte(toI2PBase64(keyPair[:100]))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ConvertKeys(tt.input); !errors.Is(err, ErrKeyTooShort) {
+				t.Errorf("got %v, want ErrKeyTooShort", err)
+			}
+		})
+	}
+}
+
+func TestFormatAllKeys(t *testing.T) {
+	var lines, want []string
+	for _, sigType := range testSigTypes {
+		keyPair := testKeyPair(t, sigType)
+		formatted, err := ConvertKeys(keyPair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, toI2PBase64(keyPair))
+		want = append(want, string(formatted))
+	}
+
+	got, err := FormatAllKeys([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != strings.Join(want, "\n\n") {
+		t.Errorf("got\n%s\nwant one two-line block per key", got)
+	}
+}
+
+// Two-line keys as editors on other systems save them must be recognised and rewritten with
+// bare LF line endings, and nothing but the two lines
+func TestConvertKeysLineVariants(t *testing.T) {
+	keyPair := testKeyPair(t, 7)
+	formatted, err := ConvertKeys(keyPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(formatted), "\n")
+	dest, full := lines[0], lines[1]
+
+	tests := []struct {
+		name    string
+		input   string
+		correct bool // What IsCorrectFormat should report; ConvertKeys strips a BOM before checking
+	}{
+		{"CRLF", dest + "\r\n" + full, true},
+		{"CRLF with a trailing CRLF", dest + "\r\n" + full + "\r\n", true},
+		{"trailing newline", dest + "\n" + full + "\n", true},
+		{"trailing blank lines", dest + "\n" + full + "\n\n\n", true},
+		{"leading blank line", "\n" + dest + "\n" + full, true},
+		{"whitespace around and between the lines", "  \n " + dest + " \n\n\t" + full + " \n", true},
+		{"UTF-8 byte order mark", utf8BOM + dest + "\n" + full + "\n", false},
+		{"byte order mark and CRLF", utf8BOM + dest + "\r\n" + full + "\r\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCorrectFormat(tt.input); got != tt.correct {
+				t.Errorf("IsCorrectFormat = %v, want %v", got, tt.correct)
+			}
+
+			got, err := ConvertKeys([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSuffix(string(got), "\n") != string(formatted) {
+				t.Errorf("got %q, want %q", got, formatted)
+			}
+			checkTwoLine(t, got, keyPair)
+		})
+	}
+}
+
+// fuzzSeedKeys returns the destination and full key lines of a real key of each tested
+// signing type, for seeding the fuzz targets
+func fuzzSeedKeys(f *testing.F) [][2]string {
+	var keys [][2]string
+	for _, sigType := range testSigTypes {
+		formatted, err := ConvertKeys(testKeyPair(f, sigType))
+		if err != nil {
+			f.Fatal(err)
+		}
+		dest, full, _ := strings.Cut(string(formatted), "\n")
+		keys = append(keys, [2]string{dest, full})
+	}
+	return keys
+}
+
+// FuzzFromI2PBase64 checks that decoding arbitrary text never panics
+func FuzzFromI2PBase64(f *testing.F) {
+	// 516 characters is a NULL-certificate destination, so cutting longer lines there
+	// leaves a KEY certificate or a private key short
+	for _, key := range fuzzSeedKeys(f) {
+		dest, full := key[0], key[1]
+		f.Add(dest)
+		f.Add(full)
+		f.Add(dest[:516])
+		f.Add(full[:516])
+	}
+	f.Add("")
+	f.Add("====")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		fromI2PBase64(data)
+	})
+}
+
+// FuzzIsCorrectFormat checks that IsCorrectFormat never panics, and never accepts lines that
+// fromI2PBase64 can't decode
+func FuzzIsCorrectFormat(f *testing.F) {
+	for _, key := range fuzzSeedKeys(f) {
+		dest, full := key[0], key[1]
+		f.Add(dest + "\n" + full)
+		f.Add(dest + "\r\n" + full + "\r\n")
+		f.Add(dest[:516] + "\n" + full)
+		f.Add(dest + "\n" + full[:516])
+		f.Add(dest[:516])
+	}
+	f.Add("")
+	f.Add("\n\n")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		if !IsCorrectFormat(data) {
+			return
+		}
+		for i, line := range keyLines(data) {
+			if _, err := fromI2PBase64(line); err != nil {
+				t.Fatalf("IsCorrectFormat accepted line %d that doesn't decode: %v", i+1, err)
+			}
+		}
+	})
+}
+
+// benchmarkInputs are an already formatted key, which both checks have to read in full, and
+// a binary keypair, which looksFormatted turns away early
+func benchmarkInputs(b *testing.B) map[string][]byte {
+	keyPair := testKeyPair(b, 7)
+	formatted, err := ConvertKeys(keyPair)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return map[string][]byte{"formatted": formatted, "binary": keyPair}
+}
+
+func BenchmarkLooksFormatted(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				looksFormatted(data)
+			}
+		})
+	}
+}
+
+func BenchmarkIsCorrectFormat(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				IsCorrectFormat(string(data))
+			}
+		})
+	}
+}
+
+func BenchmarkIsCorrectFormatBytes(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				IsCorrectFormatBytes(data)
+			}
+		})
+	}
+}
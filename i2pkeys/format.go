@@ -0,0 +1,109 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// Kinds of input CheckFormat tells apart, for FormatInfo.DetectedType
+const (
+	FormatTwoLine    = "two-line"    // The two-line format, correct or not
+	FormatSingleLine = "single-line" // One I2P Base64 line of the full keypair
+	FormatWrapped    = "wrapped"     // The full keypair in I2P Base64 wrapped at a fixed width
+	FormatMultiKey   = "multi-key"   // Several two-line keys one after another
+	FormatStdBase64  = "std-base64"  // Base64 in the standard alphabet rather than I2P's
+	FormatHosts      = "hosts"       // A hosts.txt style name=base64 line
+	FormatPEM        = "pem"         // I2P PEM blocks
+	FormatHex        = "hex"         // A hex dump of the keypair
+	FormatGzip       = "gzip"        // Gzip-compressed data, not looked into
+	FormatZip        = "zip"         // A zip archive, not looked into
+	FormatBinary     = "binary"      // The raw keypair bytes
+	FormatUnknown    = "unknown"     // Nothing recognisable as a key
+)
+
+// FormatInfo is CheckFormat's assessment of key data. The line fields describe the first two
+// non-empty lines of text input and are false for binary input.
+type FormatInfo struct {
+	LineCount     int    `json:"line_count"`      // Non-empty lines, 0 for binary input
+	Line1Valid    bool   `json:"line1_valid"`     // Line 1 decodes to a well-formed destination
+	Line2Valid    bool   `json:"line2_valid"`     // Line 2 decodes to a destination followed by private keys
+	Line1IsPrefix bool   `json:"line1_is_prefix"` // Line 1 is the destination at the start of line 2
+	LinesSwapped  bool   `json:"lines_swapped"`   // Line 2 is the destination at the start of line 1, see Reformat
+	DetectedType  string `json:"detected_type"`   // One of the Format constants
+}
+
+// CheckFormat assesses key data without converting it, to show a user what is wrong with a
+// file line by line. The error is nil when the data is a correct two-line key whose lines
+// agree, and otherwise says why it is not, as ValidateFormat does.
+func CheckFormat(data []byte) (FormatInfo, error) {
+	info := FormatInfo{DetectedType: detectFormat(data)}
+
+	switch info.DetectedType {
+	case FormatBinary, FormatGzip, FormatZip:
+	default:
+		lines := keyLines(string(stripBOM(data)))
+		info.LineCount = len(lines)
+
+		var destination, fullKey []byte
+		if len(lines) > 0 {
+			destination, _ = fromI2PBase64(lines[0])
+			info.Line1Valid = destination != nil && ValidateDestination(destination) == nil
+		}
+		if len(lines) > 1 {
+			fullKey, _ = fromI2PBase64(lines[1])
+			keyPair, err := newKeyPair(fullKey)
+			info.Line2Valid = err == nil && len(keyPair.PrivateKey) > 0
+		}
+		info.Line1IsPrefix = info.Line1Valid && checkDestinationPrefix(destination, fullKey) == nil
+		info.LinesSwapped = LinesSwapped(data)
+	}
+
+	return info, ValidateFormat(stripBOM(data))
+}
+
+// detectFormat names the kind of input data is, checking in the order ConvertKeys decodes
+func detectFormat(data []byte) string {
+	switch {
+	case IsZip(data):
+		return FormatZip
+	case bytes.HasPrefix(data, gzipMagic):
+		return FormatGzip
+	}
+
+	data = stripBOM(data)
+	text := string(data)
+	switch {
+	case isPEM(data):
+		return FormatPEM
+	case isTwoLine(text), LinesSwapped(data):
+		return FormatTwoLine
+	}
+
+	if _, _, ok := splitHostsLine(text); ok {
+		return FormatHosts
+	}
+	if decoded, ok := decodeHexDump(text); ok && isPlausibleKey(decoded) {
+		return FormatHex
+	}
+	if unwrapped, ok := unwrapBase64(text); ok {
+		if decoded, err := fromI2PBase64(unwrapped); err == nil && isPlausibleKey(decoded) {
+			return FormatWrapped
+		}
+	}
+	if lines := keyLines(text); len(lines) > 2 && len(lines)%2 == 0 && allI2PBase64(lines) {
+		return FormatMultiKey
+	}
+	if line := strings.TrimSpace(text); isI2PBase64Format(line) {
+		if decoded, err := fromI2PBase64(line); err == nil && isPlausibleKey(decoded) {
+			return FormatSingleLine
+		}
+	}
+	if errors.Is(CheckAlphabet(text), ErrAlphabetMismatch) {
+		return FormatStdBase64
+	}
+	if isPlausibleKey(data) {
+		return FormatBinary
+	}
+	return FormatUnknown
+}
@@ -0,0 +1,60 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Gzip streams start with these two magic bytes
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Key files are small, so anything that inflates past this is not a key archive
+const maxDecompressedSize = 1 << 20
+
+// DecompressKeyData returns gzip-compressed data decompressed, and any other data unchanged
+func DecompressKeyData(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip data: %w", err)
+	}
+	defer reader.Close()
+
+	decompressed, err := readLimited(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip data: %w", err)
+	}
+	return decompressed, nil
+}
+
+// readLimited reads r to the end, failing once it passes maxDecompressedSize. Compressed
+// input can inflate to any size, so gzip streams and zip entries are read through it.
+func readLimited(r io.Reader) ([]byte, error) {
+	// Read one byte past the limit to tell a full-size key from an oversized input
+	data, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDecompressedSize {
+		return nil, fmt.Errorf("more than %d bytes, too large for a key file", maxDecompressedSize)
+	}
+	return data, nil
+}
+
+// CompressKeyData gzip-compresses key data
+func CompressKeyData(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	writer := gzip.NewWriter(&out)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress key data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress key data: %w", err)
+	}
+	return out.Bytes(), nil
+}
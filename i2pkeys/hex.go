@@ -0,0 +1,37 @@
+package i2pkeys
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// decodeHex decodes hex text, ignoring whitespace so wrapped dumps such as xxd -p's read too
+func decodeHex(text string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHex, err)
+	}
+	return decoded, nil
+}
+
+// decodeHexDump returns the bytes of a hex dump, reporting false for anything that is not an
+// even number of hex digits, whitespace aside
+func decodeHexDump(text string) ([]byte, bool) {
+	digits := 0
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F':
+			digits++
+		case r == ' ', r == '\t', r == '\n', r == '\r':
+		default:
+			return nil, false
+		}
+	}
+	if digits == 0 || digits%2 != 0 {
+		return nil, false
+	}
+
+	decoded, err := decodeHex(text)
+	return decoded, err == nil
+}
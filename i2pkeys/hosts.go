@@ -0,0 +1,58 @@
+package i2pkeys
+
+import "strings"
+
+// splitHostsLine splits a hosts.txt style "name=base64" line into the hostname and the key,
+// dropping any "#!" metadata after the key. ok is false when the line is not in that form.
+func splitHostsLine(line string) (hostname, key string, ok bool) {
+	line = strings.TrimSpace(line)
+
+	// Padded Base64 also contains '=', so only look for a name when the line isn't a key already
+	if strings.Contains(line, "\n") || isI2PBase64Format(line) {
+		return "", "", false
+	}
+
+	hostname, key, found := strings.Cut(line, "=")
+	if !found || !isHostname(hostname) {
+		return "", "", false
+	}
+
+	// Addressbook dumps may append "#!key=value" properties
+	if metadata := strings.Index(key, "#!"); metadata >= 0 {
+		key = key[:metadata]
+	}
+	key = strings.TrimSpace(key)
+
+	if !isI2PBase64Format(key) {
+		return "", "", false
+	}
+	return hostname, key, true
+}
+
+// isHostname reports whether name only uses the characters allowed in an I2P hostname
+func isHostname(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !((r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') ||
+			r == '.' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripHostname returns the key half of each hosts-style line, leaving other lines unchanged
+func stripHostname(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = line
+		if _, key, ok := splitHostsLine(line); ok {
+			keys[i] = key
+		}
+	}
+	return keys
+}
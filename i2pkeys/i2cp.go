@@ -0,0 +1,73 @@
+package i2pkeys
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// I2CP messages start with a 4-byte body length and a 1-byte message type
+const i2cpHeaderLength = 5
+
+// I2CP message types that carry a SessionConfig
+const (
+	i2cpCreateSession      = 1
+	i2cpReconfigureSession = 2
+)
+
+// Size of the session ID that precedes the SessionConfig in a ReconfigureSession message
+const i2cpSessionIDLength = 2
+
+// Size of the Date after the SessionConfig options mapping
+const i2cpDateLength = 8
+
+// ParseI2CPSessionKeys reads the destination from an I2CP CreateSession or ReconfigureSession
+// message, skipping the message header and the session ID. A SessionConfig is signed by the
+// destination but holds no private keys, so the returned KeyPair has an empty PrivateKey.
+func ParseI2CPSessionKeys(data []byte) (*KeyPair, error) {
+	config, ok := i2cpSessionConfig(data)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an I2CP CreateSession or ReconfigureSession message", ErrInvalidFormat)
+	}
+
+	destLength, err := certLength(config)
+	if err != nil {
+		return nil, fmt.Errorf("I2CP SessionConfig destination: %w", err)
+	}
+
+	// The destination is followed by the options mapping, the date and the signature
+	rest := config[destLength:]
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("%w: I2CP SessionConfig ends before its options", ErrKeyTooShort)
+	}
+	mappingLength := int(binary.BigEndian.Uint16(rest))
+	if len(rest) < 2+mappingLength+i2cpDateLength {
+		return nil, fmt.Errorf("%w: I2CP SessionConfig declares %d bytes of options but only %d remain",
+			ErrKeyTooShort, mappingLength, len(rest)-2)
+	}
+
+	debugf("detected I2CP SessionConfig, destination %d bytes, options %d bytes", destLength, mappingLength)
+	return newKeyPair(config[:destLength])
+}
+
+// i2cpSessionConfig returns the SessionConfig of a framed I2CP message that carries one
+func i2cpSessionConfig(data []byte) ([]byte, bool) {
+	if len(data) < i2cpHeaderLength {
+		return nil, false
+	}
+
+	// The declared body length has to account for exactly the rest of the data
+	bodyLength := binary.BigEndian.Uint32(data)
+	if uint64(bodyLength) != uint64(len(data)-i2cpHeaderLength) {
+		return nil, false
+	}
+
+	body := data[i2cpHeaderLength:]
+	switch data[4] {
+	case i2cpCreateSession:
+		return body, len(body) >= destinationLength
+	case i2cpReconfigureSession:
+		return body[min(i2cpSessionIDLength, len(body)):], len(body) >= i2cpSessionIDLength+destinationLength
+	default:
+		return nil, false
+	}
+}
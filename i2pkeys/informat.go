@@ -0,0 +1,63 @@
+package i2pkeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Input encodings a caller can declare instead of having them detected
+const (
+	InputAuto      = ""       // Detect the encoding, as ConvertKeys does
+	InputBinary    = "binary" // The raw keypair bytes
+	InputI2PBase64 = "i2pb64" // The keypair in I2P Base64
+	InputStdBase64 = "stdb64" // The keypair in standard Base64, as written by non-I2P tools
+	InputHex       = "hex"    // The keypair as hex digits, as debug and capture tools dump it
+)
+
+// DecodeInput decodes a single keypair in the declared encoding, without any detection.
+// Line breaks inside Base64 input, and any whitespace in hex, are ignored.
+func DecodeInput(data []byte, format string) ([]byte, error) {
+	switch format {
+	case InputAuto:
+		return decodeKeyData(data)
+	case InputBinary:
+		return data, nil
+	case InputI2PBase64:
+		decoded, err := fromI2PBase64(strings.TrimSpace(string(stripBOM(data))))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+		}
+		return decoded, nil
+	case InputStdBase64:
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(stripBOM(data))))
+		if err != nil {
+			return nil, fmt.Errorf("%w: standard Base64: %v", ErrInvalidBase64, err)
+		}
+		return decoded, nil
+	case InputHex:
+		return decodeHex(string(stripBOM(data)))
+	default:
+		return nil, fmt.Errorf("unknown input format %q, expected binary, i2pb64, stdb64 or hex", format)
+	}
+}
+
+// ConvertKeysAs is ConvertKeys for input in a declared encoding. InputAuto detects it as
+// ConvertKeys does; any other format is decoded as given and never guessed at.
+func ConvertKeysAs(data []byte, format string) ([]byte, error) {
+	if format == InputAuto {
+		return ConvertKeys(data)
+	}
+
+	debugf("input format declared as %s", format)
+	fullKey, err := DecodeInput(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedOutput, err := formatKeyPair(fullKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract public key portion: %w", err)
+	}
+	return []byte(formattedOutput), nil
+}
@@ -0,0 +1,227 @@
+package i2pkeys
+
+import "fmt"
+
+// Router implementations whose private key file layouts are understood
+const (
+	ImplJava = "java"
+	ImplI2PD = "i2pd"
+)
+
+// Size of the encryption private key slot i2pd uses for every crypto type except ECIES-X25519
+const i2pdPrivateKeySlot = 256
+
+// keyFileLayout holds what the destination's certificate says about a private key file
+type keyFileLayout struct {
+	decoded    []byte
+	destLength int
+	sigInfo    signingKeyType
+	cryptoType uint16
+	cryptoInfo cryptoKeyType
+}
+
+// ParseJavaPrivateKeyFile parses the private key file layout written by Java I2P's PrivateKeyFile:
+//
+//	destination            (384 bytes + certificate)
+//	encryption private key (sized by the certificate's crypto type, 256 bytes for ElGamal)
+//	signing private key    (sized by the certificate's signing type, 20 bytes for DSA-SHA1)
+//
+// Any bytes after the signing private key, such as an offline signature block, are kept in PrivateKey.
+func ParseJavaPrivateKeyFile(data []byte) (*KeyPair, error) {
+	return parseKeyFile(data, ImplJava)
+}
+
+// ParseI2PDKeys parses the private key file layout written by i2pd's PrivateKeys::ToBuffer:
+//
+//	destination            (384 bytes + certificate)
+//	encryption private key (always 256 bytes, except 32 bytes for ECIES-X25519)
+//	signing private key    (sized by the certificate's signing type)
+//
+// The layouts only differ for the EC-P256/P384/P521 crypto types, whose private
+// keys i2pd stores in a 256-byte slot where Java I2P uses the key's own size.
+func ParseI2PDKeys(data []byte) (*KeyPair, error) {
+	return parseKeyFile(data, ImplI2PD)
+}
+
+// ParseKeysFor parses a private key file using the layout of impl, detecting it when impl is empty
+func ParseKeysFor(data []byte, impl string) (*KeyPair, error) {
+	if impl == "" {
+		detected, err := DetectImplementation(data)
+		if err != nil {
+			return nil, err
+		}
+		impl = detected
+	}
+	return parseKeyFile(data, impl)
+}
+
+// DetectImplementation guesses which router implementation wrote a private key file.
+// Key files whose layout is the same for both, such as ElGamal or ECIES-X25519 keys,
+// are reported as Java I2P.
+func DetectImplementation(data []byte) (string, error) {
+	layout, err := readKeyFileLayout(data)
+	if err != nil {
+		return "", err
+	}
+
+	javaLength, err := layout.privateLength(ImplJava)
+	if err != nil {
+		return "", err
+	}
+	i2pdLength, err := layout.privateLength(ImplI2PD)
+	if err != nil {
+		return "", err
+	}
+
+	// Only an exact i2pd-sized private section points away from the Java layout
+	remaining := len(layout.decoded) - layout.destLength
+	debugf("%s and %s private keys: %d bytes after the destination, java layout needs %d, i2pd %d",
+		layout.cryptoInfo.name, layout.sigInfo.name, remaining, javaLength, i2pdLength)
+	if i2pdLength != javaLength && remaining == i2pdLength {
+		return ImplI2PD, nil
+	}
+	return ImplJava, nil
+}
+
+// parseKeyFile splits a private key file into its components using the layout of impl
+func parseKeyFile(data []byte, impl string) (*KeyPair, error) {
+	layout, err := readKeyFileLayout(data)
+	if err != nil {
+		return nil, err
+	}
+
+	privateLength, err := layout.privateLength(impl)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, destLength := layout.decoded, layout.destLength
+	if remaining := len(decoded) - destLength; remaining < privateLength {
+		return nil, fmt.Errorf("%w: %s and %s private keys need %d bytes after the destination in %s layout, only %d present",
+			ErrKeyTooShort, layout.cryptoInfo.name, layout.sigInfo.name, privateLength, impl, remaining)
+	}
+
+	keyPair := &KeyPair{
+		PublicKey:  decoded[:destLength],
+		PrivateKey: decoded[destLength:],
+		FullData:   decoded,
+	}
+	layout.fillComponents(keyPair, impl)
+	return keyPair, nil
+}
+
+// readKeyFileLayout decodes key data and looks up the key types its certificate declares
+func readKeyFileLayout(data []byte) (*keyFileLayout, error) {
+	decoded, err := decodeKeyData(data)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyFileLayout(decoded)
+}
+
+// newKeyFileLayout looks up the key types declared by the certificate of raw key bytes
+func newKeyFileLayout(decoded []byte) (*keyFileLayout, error) {
+	destLength, err := certLength(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificate determines the size of both private keys
+	sigType, cryptoType, err := destinationKeyTypes(decoded[:destLength])
+	if err != nil {
+		return nil, err
+	}
+	sigInfo, err := lookupSigningKeyType(sigType)
+	if err != nil {
+		return nil, err
+	}
+	cryptoInfo, err := lookupCryptoKeyType(cryptoType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyFileLayout{
+		decoded:    decoded,
+		destLength: destLength,
+		sigInfo:    sigInfo,
+		cryptoType: cryptoType,
+		cryptoInfo: cryptoInfo,
+	}, nil
+}
+
+// privateLength returns the combined size of the encryption and signing private keys in impl's layout
+func (l *keyFileLayout) privateLength(impl string) (int, error) {
+	switch impl {
+	case ImplJava:
+		return l.cryptoInfo.privateKeyLength + l.sigInfo.privateKeyLength, nil
+	case ImplI2PD:
+		return l.encryptionPrivateKeyLength(impl) + l.sigInfo.privateKeyLength, nil
+	default:
+		return 0, fmt.Errorf("unknown implementation %q, expected java or i2pd", impl)
+	}
+}
+
+// encryptionPrivateKeyLength returns the size of the encryption private key slot in impl's layout
+func (l *keyFileLayout) encryptionPrivateKeyLength(impl string) int {
+	if impl == ImplI2PD && l.cryptoType != cryptoTypeX25519 {
+		return i2pdPrivateKeySlot
+	}
+	return l.cryptoInfo.privateKeyLength
+}
+
+// fillComponents sets the individual keys of keyPair. The crypto public key sits at the start
+// of its 256-byte slot, and the signing public key is placed as signingPublicKey describes.
+func (l *keyFileLayout) fillComponents(keyPair *KeyPair, impl string) {
+	decoded := l.decoded
+
+	keyPair.EncryptionPublicKey = decoded[:l.cryptoInfo.publicKeyLength]
+	if signingKey, err := l.signingPublicKey(); err == nil {
+		keyPair.SigningPublicKey = signingKey
+	}
+
+	encryptionOffset, signingOffset, ok := l.privateKeyOffsets(impl)
+	if !ok {
+		return
+	}
+	keyPair.EncryptionPrivateKey = decoded[encryptionOffset : encryptionOffset+l.cryptoInfo.privateKeyLength]
+	keyPair.SigningPrivateKey = decoded[signingOffset : signingOffset+l.sigInfo.privateKeyLength]
+}
+
+// privateKeyOffsets returns where the encryption and signing private keys start in impl's
+// layout. Private keys follow the destination, encryption key first, each in its slot; ok is
+// false when the data is too short to hold both.
+func (l *keyFileLayout) privateKeyOffsets(impl string) (encryption, signing int, ok bool) {
+	encryption = l.destLength
+	signing = encryption + l.encryptionPrivateKeyLength(impl)
+	return encryption, signing, len(l.decoded) >= signing+l.sigInfo.privateKeyLength
+}
+
+// signingKeyStart returns the offset of the signing public key: the end of its slot, or the
+// start of the slot for keys that continue in the certificate
+func (l *keyFileLayout) signingKeyStart() int {
+	return certificateOffset - min(l.sigInfo.publicKeyLength, signingKeySlotLength)
+}
+
+// signingPublicKey returns the signing public key, which sits at the end of its 128-byte slot.
+// Only keys longer than the slot, such as ECDSA-P521 and RSA, continue in the KEY certificate
+// after the two type fields; Ed25519 and the other short keys fit in the slot entirely.
+func (l *keyFileLayout) signingPublicKey() ([]byte, error) {
+	sigLength := l.sigInfo.publicKeyLength
+	if sigLength <= signingKeySlotLength {
+		return l.decoded[l.signingKeyStart():certificateOffset], nil
+	}
+
+	cert, err := ParseCertificate(l.decoded)
+	if err != nil {
+		return nil, err
+	}
+	excess := sigLength - signingKeySlotLength
+	if len(cert.Payload) < 4+excess {
+		return nil, fmt.Errorf("%w: %s key needs %d bytes in the KEY certificate, only %d present",
+			ErrInvalidCertificate, l.sigInfo.name, excess, max(len(cert.Payload)-4, 0))
+	}
+
+	signingKey := make([]byte, 0, sigLength)
+	signingKey = append(signingKey, l.decoded[l.signingKeyStart():certificateOffset]...)
+	return append(signingKey, cert.Payload[4:4+excess]...), nil
+}
@@ -0,0 +1,313 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseKeyPair decodes a binary, Base64 or two-line key blob into its components
+func ParseKeyPair(data []byte) (*KeyPair, error) {
+	decoded, err := decodeKeyData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := newKeyPair(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the alias of a hosts-style "name=base64" line
+	if hostname, _, ok := splitHostsLine(string(data)); ok {
+		keyPair.Hostname = hostname
+	}
+
+	return keyPair, nil
+}
+
+// newKeyPair splits raw key bytes into a KeyPair
+func newKeyPair(decoded []byte) (*KeyPair, error) {
+	// The certificate determines where the destination ends
+	destLength, err := certLength(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair := &KeyPair{
+		PublicKey:  decoded[:destLength],
+		PrivateKey: decoded[destLength:],
+		FullData:   decoded,
+	}
+
+	// Split out the individual keys when the certificate's key types are known
+	if layout, err := newKeyFileLayout(decoded); err == nil {
+		layout.fillComponents(keyPair, ImplJava)
+	}
+
+	return keyPair, nil
+}
+
+// CombineKeyPair joins raw destination bytes and the private keys stored apart from it,
+// the inverse of splitting PublicKey from PrivateKey. The destination must be complete
+// and, when its key types are known, the private keys long enough for them.
+func CombineKeyPair(destination, privateKey []byte) (*KeyPair, error) {
+	if err := ValidateDestination(destination); err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	if len(privateKey) == 0 {
+		return nil, ErrNoPrivateKey
+	}
+
+	fullKey := make([]byte, 0, len(destination)+len(privateKey))
+	fullKey = append(fullKey, destination...)
+	fullKey = append(fullKey, privateKey...)
+
+	// Java I2P needs the fewest bytes, so anything shorter fits neither layout
+	if layout, err := newKeyFileLayout(fullKey); err == nil {
+		if needed, _ := layout.privateLength(ImplJava); len(privateKey) < needed {
+			return nil, fmt.Errorf("%w: %s and %s private keys need %d bytes, only %d given",
+				ErrKeyTooShort, layout.cryptoInfo.name, layout.sigInfo.name, needed, len(privateKey))
+		}
+	}
+
+	return newKeyPair(fullKey)
+}
+
+// SplitKeyPair returns the destination and the private keys of a keypair in any supported form,
+// split where the certificate says the destination ends, for storing the two apart. It is the
+// inverse of CombineKeyPair. Data without private keys fails with ErrNoPrivateKey.
+func SplitKeyPair(data []byte) (dest, priv []byte, err error) {
+	keyPair, err := ParseKeyPair(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keyPair.PrivateKey) == 0 {
+		return nil, nil, ErrNoPrivateKey
+	}
+	return bytes.Clone(keyPair.PublicKey), bytes.Clone(keyPair.PrivateKey), nil
+}
+
+// Format returns the two-line encoding of the key pair
+func (k *KeyPair) Format() ([]byte, error) {
+	formattedOutput, err := formatKeyPair(k.FullData)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(formattedOutput), nil
+}
+
+// ExtractDestination returns just the destination bytes of a key blob, without the private keys
+func ExtractDestination(data []byte) ([]byte, error) {
+	keyPair, err := ParseKeyPair(data)
+	if err != nil {
+		return nil, err
+	}
+	return keyPair.PublicKey, nil
+}
+
+// FormatDestination returns the I2P Base64 destination line of a key blob, safe to publish
+func FormatDestination(data []byte) ([]byte, error) {
+	destination, err := ExtractDestination(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(toI2PBase64(destination)), nil
+}
+
+// ToSingleLine returns a keypair in any supported form as one I2P Base64 line of the full key,
+// line 2 of the two-line format without the destination line before it. Tools that derive the
+// destination themselves take this form, and ConvertKeys turns it back into the two lines.
+func ToSingleLine(data []byte) ([]byte, error) {
+	keyPair, err := ParseKeyPair(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyPair.PrivateKey) == 0 {
+		return nil, ErrNoPrivateKey
+	}
+	return []byte(toI2PBase64(keyPair.FullData) + "\n"), nil
+}
+
+// RedactPrivateKey returns the destination of a keypair in any supported form as a single
+// I2P Base64 line, the form destinations are published in. Nothing of the private keys is
+// kept, so the result is safe to share, and it reads back as a destination on its own.
+func RedactPrivateKey(data []byte) ([]byte, error) {
+	line, err := FormatDestination(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// MarshalJSON describes the key pair without exposing any private key material
+func (k *KeyPair) MarshalJSON() ([]byte, error) {
+	address, err := Base32Address(k.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sigType, err := SigningKeyType(k.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Destination       string `json:"destination"`
+		Base32Address     string `json:"base32_address"`
+		SigningKeyType    string `json:"signing_key_type"`
+		DestinationLength int    `json:"destination_length"`
+		FullKeyLength     int    `json:"full_key_length"`
+	}{
+		Destination:       toI2PBase64(k.PublicKey),
+		Base32Address:     address,
+		SigningKeyType:    sigType,
+		DestinationLength: len(k.PublicKey),
+		FullKeyLength:     len(k.FullData),
+	})
+}
+
+// decodeKeyData returns the raw key bytes from binary, Base64, hosts-style, PEM or two-line input
+func decodeKeyData(data []byte) ([]byte, error) {
+	data = stripBOM(data)
+	keyData := string(data)
+
+	// Drop the alias from a hosts-style "name=base64" line
+	if hostname, key, ok := splitHostsLine(keyData); ok {
+		debugf("detected hosts-style line for %s", hostname)
+		keyData = key
+		data = []byte(key)
+	}
+
+	// PEM blocks hold the raw bytes
+	if isPEM(data) {
+		debugf("detected PEM blocks")
+		return decodePEM(data)
+	}
+
+	// Debug tools dump keys as hex, which has to be caught before it is taken for wrapped
+	// Base64; Base64 of a real key practically never uses hex digits alone
+	if decoded, ok := decodeHexDump(keyData); ok && isPlausibleKey(decoded) {
+		debugf("detected hex dump, decodes to %d bytes", len(decoded))
+		return decoded, nil
+	}
+
+	// A backwards two-line key would otherwise be joined as if wrapped, or read as two keys
+	if LinesSwapped(data) {
+		return nil, fmt.Errorf("%w: line 2 is the destination at the start of line 1", ErrSwappedLines)
+	}
+
+	// Exports often wrap Base64 at 64 or 76 columns
+	if unwrapped, ok := unwrapBase64(keyData); ok {
+		debugf("joined Base64 wrapped at a fixed width")
+		keyData = unwrapped
+		data = []byte(unwrapped)
+	}
+
+	// In two-line format the second line holds the full keypair
+	if isTwoLine(keyData) {
+		lines := keyLines(keyData)
+		decoded, err := fromI2PBase64(strings.TrimSpace(lines[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: full key line: %v", ErrInvalidBase64, err)
+		}
+		debugf("detected two-line format, full key line decodes to %d bytes", len(decoded))
+		return decoded, nil
+	}
+
+	// The same two-line key written out several times is still that one key
+	if lines := keyLines(keyData); repeatedTwoLine(lines) {
+		debugf("detected a two-line key repeated %d times", len(lines)/2)
+		return fromI2PBase64(lines[1])
+	}
+
+	// A single I2P Base64 line is the full keypair, provided it decodes cleanly to something
+	// shaped like one. Binary data that happens to use only Base64 characters fails these checks.
+	if isI2PBase64Format(keyData) {
+		decoded, err := decodeBase64(i2pEncoding().Strict(), keyData)
+		if err == nil && isPlausibleKey(decoded) {
+			debugf("detected single-line I2P Base64, decodes to %d bytes", len(decoded))
+			return decoded, nil
+		}
+
+		// A clean Base64 line too short for a destination is a truncated key, not binary data,
+		// unless the bytes happen to read as a key themselves
+		if err == nil && len(decoded) < destinationLength && !isPlausibleKey(data) {
+			return nil, fmt.Errorf("%w: I2P Base64 line decodes to %d bytes, need at least %d for a destination",
+				ErrKeyTooShort, len(decoded), destinationLength)
+		}
+		debugf("input uses only I2P Base64 characters but does not decode to a key, so it is not Base64")
+	}
+
+	// The header of an I2CP session message would otherwise be read as the start of the destination
+	if _, ok := i2cpSessionConfig(data); ok {
+		return nil, fmt.Errorf("%w: it holds a destination but no private keys (see ParseI2CPSessionKeys)", ErrI2CPSessionConfig)
+	}
+
+	// A zip archive would otherwise be read as a destination made of its header bytes
+	if IsZip(data) {
+		return nil, fmt.Errorf("%w (see ConvertZip)", ErrZipArchive)
+	}
+
+	// Standard Base64 would otherwise be read as binary and fail on a certificate made of its characters
+	if err := CheckAlphabet(keyData); err != nil {
+		return nil, err
+	}
+
+	// Otherwise treat the input as the raw binary keypair
+	debugf("treating input as raw binary, %d bytes", len(data))
+	return data, nil
+}
+
+// isPlausibleKey reports whether decoded is long enough for a destination and its certificate fits
+func isPlausibleKey(decoded []byte) bool {
+	_, err := certLength(decoded)
+	return err == nil
+}
+
+// formatKeyPair builds the two-line format from the raw keypair bytes
+func formatKeyPair(fullKey []byte) (string, error) {
+	destLength, err := certLength(fullKey)
+	if err != nil {
+		return "", err
+	}
+
+	if cert, err := ParseCertificate(fullKey); err == nil {
+		debugf("certificate type %d %s, %d bytes of payload", cert.Type, cert.TypeName(), cert.Length)
+	}
+	debugf("destination length %d, private key %d bytes", destLength, len(fullKey)-destLength)
+
+	// A destination repeated as line 2 would claim private keys that aren't there
+	if destLength == len(fullKey) {
+		return "", ErrNoPrivateKey
+	}
+
+	// Line 1 is the destination, line 2 the complete keypair
+	formattedOutput := toI2PBase64(fullKey[:destLength]) + "\n" + toI2PBase64(fullKey)
+	if err := verifyFormatted(formattedOutput, fullKey, destLength); err != nil {
+		return "", err
+	}
+
+	return formattedOutput, nil
+}
+
+// verifyFormatted decodes a freshly built two-line key and confirms it matches the source bytes
+func verifyFormatted(formattedOutput string, fullKey []byte, destLength int) error {
+	lines := strings.Split(formattedOutput, "\n")
+	if len(lines) != 2 {
+		return fmt.Errorf("%w: formatted key has %d lines", ErrRoundTrip, len(lines))
+	}
+
+	decodedFull, err := fromI2PBase64(lines[1])
+	if err != nil || !bytes.Equal(decodedFull, fullKey) {
+		return fmt.Errorf("%w: full key line", ErrRoundTrip)
+	}
+
+	decodedDest, err := fromI2PBase64(lines[0])
+	if err != nil || !bytes.Equal(decodedDest, fullKey[:destLength]) {
+		return fmt.Errorf("%w: destination line", ErrRoundTrip)
+	}
+
+	return nil
+}
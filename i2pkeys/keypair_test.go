@@ -0,0 +1,82 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A Base64 line too short for a destination used to be taken for raw binary and fail on the
+// certificate. It must be read as Base64 and reported as too short.
+func TestDecodeKeyDataShortBase64Line(t *testing.T) {
+	line := toI2PBase64(testKeyPair(t, 7)[:300])
+	if len(line) != 400 {
+		t.Fatalf("test line is %d characters, want 400", len(line))
+	}
+
+	_, err := ConvertKeys([]byte(line))
+	if !errors.Is(err, ErrKeyTooShort) {
+		t.Fatalf("got %v, want ErrKeyTooShort", err)
+	}
+	if errors.Is(err, ErrInvalidCertificate) || !strings.Contains(err.Error(), "Base64") {
+		t.Errorf("error %q does not come from decoding the line as Base64", err)
+	}
+}
+
+// Binary data made only of I2P Base64 characters must be taken for Base64 only when it decodes
+// strictly to something shaped like a key, and otherwise go down the binary path every time
+func TestDecodeKeyDataBase64CharactersOnly(t *testing.T) {
+	keyPair := testKeyPair(t, 7)
+	tests := []struct {
+		name   string
+		input  []byte
+		base64 bool // Whether the input should be decoded as Base64 rather than used as it is
+	}{
+		{"Base64 of a key", []byte(toI2PBase64(keyPair)), true},
+		{"length no Base64 can have", []byte(strings.Repeat("AbC-", 250) + "A"), false},
+		{"decodes to something that isn't a key", []byte(strings.Repeat("~", 700)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.input
+			if tt.base64 {
+				want = keyPair
+			}
+			for range 2 {
+				got, err := decodeKeyData(tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("decodeKeyData chose the wrong path: got %d bytes, want %d", len(got), len(want))
+				}
+			}
+
+			// Read as binary, the text's certificate bytes can't fit
+			_, err := ConvertKeys(tt.input)
+			switch {
+			case tt.base64 && err != nil:
+				t.Errorf("ConvertKeys: %v", err)
+			case !tt.base64 && !errors.Is(err, ErrInvalidCertificate):
+				t.Errorf("ConvertKeys: got %v, want ErrInvalidCertificate", err)
+			}
+		})
+	}
+}
+
+// A key pasted in the standard Base64 alphabet must be reported as such, not read as binary
+func TestDecodeKeyDataStandardAlphabet(t *testing.T) {
+	keyPair := testKeyPair(t, 7)
+	line := base64.StdEncoding.EncodeToString(keyPair)
+	if !strings.ContainsAny(line, "+/") {
+		t.Skip("the generated key encodes without '+' or '/'")
+	}
+
+	for _, input := range []string{line, line + "\n"} {
+		if _, err := ConvertKeys([]byte(input)); !errors.Is(err, ErrAlphabetMismatch) {
+			t.Errorf("got %v, want ErrAlphabetMismatch", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package i2pkeys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Key type codes with special meaning to the parser
+const (
+	sigTypeDSASHA1    = 0
+	cryptoTypeElGamal = 0
+	cryptoTypeX25519  = 4
+)
+
+// signingKeyType describes a signature algorithm and its key sizes in bytes
+type signingKeyType struct {
+	name             string
+	publicKeyLength  int
+	privateKeyLength int
+	signatureLength  int
+}
+
+// cryptoKeyType describes an encryption algorithm and its key sizes in bytes
+type cryptoKeyType struct {
+	name             string
+	publicKeyLength  int
+	privateKeyLength int
+}
+
+// Signing key types a KEY certificate can declare
+var signingKeyTypes = map[uint16]signingKeyType{
+	0:  {"DSA-SHA1", 128, 20, 40},
+	1:  {"ECDSA-SHA256-P256", 64, 32, 64},
+	2:  {"ECDSA-SHA384-P384", 96, 48, 96},
+	3:  {"ECDSA-SHA512-P521", 132, 66, 132},
+	4:  {"RSA-SHA256-2048", 256, 512, 256},
+	5:  {"RSA-SHA384-3072", 384, 768, 384},
+	6:  {"RSA-SHA512-4096", 512, 1024, 512},
+	7:  {"Ed25519-SHA512", 32, 32, 64},
+	8:  {"Ed25519ph-SHA512", 32, 32, 64},
+	11: {"RedDSA-SHA512", 32, 32, 64},
+}
+
+// Crypto key types a KEY certificate can declare
+var cryptoKeyTypes = map[uint16]cryptoKeyType{
+	0: {"ElGamal-2048", 256, 256},
+	1: {"EC-P256", 64, 32},
+	2: {"EC-P384", 96, 48},
+	3: {"EC-P521", 132, 66},
+	4: {"ECIES-X25519", 32, 32},
+}
+
+// Short names of the signing key types, for command line use
+var signingTypeAliases = map[string]uint16{
+	"dsa":        0,
+	"ecdsa-p256": 1,
+	"ecdsa-p384": 2,
+	"ecdsa-p521": 3,
+	"rsa-2048":   4,
+	"rsa-3072":   5,
+	"rsa-4096":   6,
+	"ed25519":    7,
+	"ed25519ph":  8,
+	"reddsa":     11,
+}
+
+// ParseSigningType returns the code of a signing key type given by its short name, such as
+// "ed25519" or "ecdsa-p256", the full name SigningKeyType reports, or its number. Case is ignored.
+func ParseSigningType(name string) (uint16, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if code, ok := signingTypeAliases[name]; ok {
+		return code, nil
+	}
+	for code, info := range signingKeyTypes {
+		if strings.ToLower(info.name) == name {
+			return code, nil
+		}
+	}
+
+	// Numbers allow for types newer than this package
+	if code, err := strconv.ParseUint(name, 10, 16); err == nil {
+		return uint16(code), nil
+	}
+	return 0, fmt.Errorf("%w: unknown signing key type %q", ErrUnsupportedKeyType, name)
+}
+
+// SupportedSigningTypes returns the name of every signing key type code the package knows,
+// as a copy the caller may change
+func SupportedSigningTypes() map[uint16]string {
+	names := make(map[uint16]string, len(signingKeyTypes))
+	for code, info := range signingKeyTypes {
+		names[code] = info.name
+	}
+	return names
+}
+
+// SupportedCryptoTypes returns the name of every crypto key type code the package knows,
+// as a copy the caller may change
+func SupportedCryptoTypes() map[uint16]string {
+	names := make(map[uint16]string, len(cryptoKeyTypes))
+	for code, info := range cryptoKeyTypes {
+		names[code] = info.name
+	}
+	return names
+}
+
+// lookupSigningKeyType returns the signing key type for a code
+func lookupSigningKeyType(code uint16) (signingKeyType, error) {
+	info, ok := signingKeyTypes[code]
+	if !ok {
+		return signingKeyType{}, fmt.Errorf("%w: unknown signing key type %d", ErrUnsupportedKeyType, code)
+	}
+	return info, nil
+}
+
+// lookupCryptoKeyType returns the crypto key type for a code
+func lookupCryptoKeyType(code uint16) (cryptoKeyType, error) {
+	info, ok := cryptoKeyTypes[code]
+	if !ok {
+		return cryptoKeyType{}, fmt.Errorf("%w: unknown crypto key type %d", ErrUnsupportedKeyType, code)
+	}
+	return info, nil
+}
+
+// keyCertificateLength returns the payload length a KEY certificate needs for its key types:
+// the two type fields, then the end of any public key too long for its slot in the destination.
+// Shorter keys, Ed25519 among them, are padded at the front of their slot and add nothing.
+func keyCertificateLength(sigInfo signingKeyType, cryptoInfo cryptoKeyType) int {
+	return 4 + max(sigInfo.publicKeyLength-signingKeySlotLength, 0) + max(cryptoInfo.publicKeyLength-publicKeySlotLength, 0)
+}
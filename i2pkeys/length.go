@@ -0,0 +1,155 @@
+package i2pkeys
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VerifyKeyLength checks that key data is exactly as long as the key types declared by its
+// certificate call for: a destination alone, or a destination followed by the private keys in
+// the Java or i2pd layout, optionally ending in a complete offline signature block. Truncated
+// or concatenated key files fail with ErrLengthMismatch.
+func VerifyKeyLength(data []byte) error {
+	layout, err := readKeyFileLayout(data)
+	if err != nil {
+		return err
+	}
+
+	if expected := layout.expectedDestinationLength(); layout.destLength != expected {
+		return fmt.Errorf("%w: a %s and %s destination is %d bytes, the certificate makes it %d",
+			ErrLengthMismatch, layout.sigInfo.name, layout.cryptoInfo.name, expected, layout.destLength)
+	}
+
+	// A public-only destination has nothing after the certificate
+	remaining := len(layout.decoded) - layout.destLength
+	if remaining == 0 {
+		return nil
+	}
+
+	expected := make([]int, 0, 2)
+	for _, impl := range []string{ImplJava, ImplI2PD} {
+		privateLength, err := layout.privateLength(impl)
+		if err != nil {
+			return err
+		}
+		if remaining == privateLength {
+			return nil
+		}
+		if offline, offset, err := layout.offlineSignature(impl); err == nil && offline.end(offset) == len(layout.decoded) {
+			return nil
+		}
+		expected = append(expected, privateLength)
+	}
+
+	sizes := fmt.Sprintf("%d bytes", expected[0])
+	if expected[1] != expected[0] {
+		sizes = fmt.Sprintf("%d bytes (java) or %d bytes (i2pd)", expected[0], expected[1])
+	}
+	return fmt.Errorf("%w: %s and %s private keys are %s after the %d-byte destination, found %d",
+		ErrLengthMismatch, layout.cryptoInfo.name, layout.sigInfo.name, sizes, layout.destLength, remaining)
+}
+
+// expectedDestinationLength returns the destination length implied by the key types. Only KEY
+// certificates have a fixed payload: the two type fields plus any key bytes too long for their slots.
+func (l *keyFileLayout) expectedDestinationLength() int {
+	if l.decoded[certificateOffset] != certTypeKey {
+		return l.destLength
+	}
+	return destinationLength + keyCertificateLength(l.sigInfo, l.cryptoInfo)
+}
+
+// ValidDestinationLengths returns, sorted, every destination length the known key types produce:
+// a NULL certificate's, and a KEY certificate's for each pair of signing and crypto types. It is
+// built from the key type tables, so it grows as key types are added.
+func ValidDestinationLengths() []int {
+	lengths := []int{destinationLength}
+	for _, sigInfo := range signingKeyTypes {
+		for _, cryptoInfo := range cryptoKeyTypes {
+			length := destinationLength + keyCertificateLength(sigInfo, cryptoInfo)
+			if !slices.Contains(lengths, length) {
+				lengths = append(lengths, length)
+			}
+		}
+	}
+	slices.Sort(lengths)
+	return lengths
+}
+
+// CheckDestinationLength fails with ErrLengthMismatch when a destination with a NULL or KEY
+// certificate is a length no known key types produce, as after a partial download or when two
+// keys were run together. Other certificate types, and KEY certificates of unknown key types,
+// have no fixed length and pass.
+func CheckDestinationLength(destination []byte) error {
+	cert, err := ParseCertificate(destination)
+	if err != nil {
+		return err
+	}
+
+	switch cert.Type {
+	case certTypeNull:
+	case certTypeKey:
+		_, sigErr := lookupSigningKeyType(cert.SigningKeyType)
+		_, cryptoErr := lookupCryptoKeyType(cert.CryptoKeyType)
+		if sigErr != nil || cryptoErr != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	valid := ValidDestinationLengths()
+	if slices.Contains(valid, len(destination)) {
+		return nil
+	}
+	lengths := make([]string, len(valid))
+	for i, length := range valid {
+		lengths[i] = strconv.Itoa(length)
+	}
+	return fmt.Errorf("%w: non-canonical destination length %d, the key may be corrupt (known lengths are %s)",
+		ErrLengthMismatch, len(destination), strings.Join(lengths, ", "))
+}
+
+// NormalizeDestination returns a copy of a destination cut or padded to the length its
+// certificate declares. Trailing bytes are dropped when they are only whitespace or zeros, as
+// left by editors and fixed-size buffers. Missing bytes are restored only for a NULL certificate,
+// which is all zeros; the missing end of any other certificate can't be known, so it is an error.
+func NormalizeDestination(dest []byte) ([]byte, error) {
+	if len(dest) > certificateOffset && len(dest) < destinationLength && dest[certificateOffset] == certTypeNull {
+		dest = append(slices.Clone(dest), make([]byte, destinationLength-len(dest))...)
+	}
+	if len(dest) < destinationLength {
+		return nil, fmt.Errorf("%w: destination is %d bytes, need at least %d", ErrKeyTooShort, len(dest), destinationLength)
+	}
+
+	declared := destinationLength + int(binary.BigEndian.Uint16(dest[certificateOffset+1:destinationLength]))
+	if len(dest) < declared {
+		return nil, fmt.Errorf("%w: the certificate makes the destination %d bytes but it has %d, and the missing certificate bytes can't be restored",
+			ErrLengthMismatch, declared, len(dest))
+	}
+
+	// certLength also checks a KEY certificate is long enough for its key types
+	expected, err := certLength(dest)
+	if err != nil {
+		return nil, err
+	}
+	if extra := dest[expected:]; !isPadding(extra) {
+		return nil, fmt.Errorf("%w: the %d-byte destination is followed by %d more that are not padding (private keys? see ExtractDestination)",
+			ErrLengthMismatch, expected, len(extra))
+	}
+	return slices.Clone(dest[:expected]), nil
+}
+
+// isPadding reports whether data is only ASCII whitespace and zero bytes
+func isPadding(data []byte) bool {
+	for _, b := range data {
+		switch b {
+		case 0, ' ', '\t', '\n', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConvertAllKeys converts every key in a multi-key file, writing one two-line block per key
+func ConvertAllKeys(inputPath, outputPath string) error {
+	// Read the key file
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	formattedOutput, err := FormatAllKeys(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputPath, err)
+	}
+
+	return WriteKeyFile(outputPath, formattedOutput, Options{})
+}
+
+// FormatAllKeys formats every key in data, separating the two-line blocks with a blank line
+func FormatAllKeys(data []byte) ([]byte, error) {
+	keyPairs, err := ParseAllKeyPairs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]string, 0, len(keyPairs))
+	for i, keyPair := range keyPairs {
+		block, err := formatKeyPair(keyPair.FullData)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	return []byte(strings.Join(blocks, "\n\n")), nil
+}
+
+// ParseAllKeyPairs parses data holding one full keypair or hosts-style entry per line, or a single key in any supported form
+func ParseAllKeyPairs(data []byte) ([]*KeyPair, error) {
+	data = stripBOM(data)
+	if unwrapped, ok := unwrapBase64(string(data)); ok {
+		data = []byte(unwrapped)
+	}
+	lines := keyLines(string(data))
+	keys := stripHostname(lines)
+
+	// Two-line, binary and single-line input all hold exactly one key
+	singleKey := len(lines) < 2 || !allI2PBase64(keys) ||
+		(len(keys) == 2 && isDestinationOf(keys[0], keys[1])) || repeatedTwoLine(keys)
+	if singleKey {
+		keyPair, err := ParseKeyPair(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*KeyPair{keyPair}, nil
+	}
+
+	keyPairs := make([]*KeyPair, 0, len(lines))
+	for i, line := range lines {
+		keyPair, err := ParseKeyPair([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		keyPairs = append(keyPairs, keyPair)
+	}
+
+	return keyPairs, nil
+}
+
+// repeatedTwoLine reports whether lines are one two-line key written out more than once,
+// as happens when formatted output is appended to the file it came from
+func repeatedTwoLine(lines []string) bool {
+	if len(lines) < 4 || len(lines)%2 != 0 || !isDestinationOf(lines[0], lines[1]) {
+		return false
+	}
+	for i := 2; i < len(lines); i += 2 {
+		if lines[i] != lines[0] || lines[i+1] != lines[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// isDestinationOf reports whether destLine decodes to the leading bytes of fullLine,
+// as line 1 does for line 2 in the two-line format
+func isDestinationOf(destLine, fullLine string) bool {
+	destination, err := fromI2PBase64(destLine)
+	if err != nil {
+		return false
+	}
+	fullKey, err := fromI2PBase64(fullLine)
+	if err != nil {
+		return false
+	}
+	return len(destination) < len(fullKey) && bytes.HasPrefix(fullKey, destination)
+}
+
+// keyLines returns the non-empty, trimmed lines of data, so blank lines, surrounding
+// whitespace and CRLF line endings are never part of a key line
+func keyLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// allI2PBase64 reports whether every line is in I2P Base64 format
+func allI2PBase64(lines []string) bool {
+	for _, line := range lines {
+		if !isI2PBase64Format(line) {
+			return false
+		}
+	}
+	return true
+}
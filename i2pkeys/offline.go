@@ -0,0 +1,93 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Size of the fixed offline signature header: 4-byte expiry + 2-byte transient signing type
+const offlineHeaderLength = 6
+
+// OfflineSignature is the offline signing block Java I2P appends to a private key file when the
+// destination's signing key is kept offline. The destination's signing private key is then all
+// zeros, and a transient key signed by the offline key is used in its place until it expires.
+type OfflineSignature struct {
+	Expires             time.Time `json:"expires"`              // When the transient key stops being valid
+	TransientSigType    uint16    `json:"transient_sig_type"`   // Signing key type code of the transient key
+	TransientKeyType    string    `json:"transient_key_type"`   // Name of the transient signing key type
+	TransientPublicKey  []byte    `json:"transient_public_key"` // Transient signing public key
+	Signature           []byte    `json:"signature"`            // Signature over the expiry, transient type and key by the destination's key
+	TransientPrivateKey []byte    `json:"-"`                    // Transient signing private key, nil when the file doesn't hold it
+}
+
+// ParseOfflineSignature decodes key data and returns the offline signature block that follows the
+// private keys, or ErrNoOfflineSignature when the key holds its signing private key directly
+func ParseOfflineSignature(data []byte) (*OfflineSignature, error) {
+	layout, err := readKeyFileLayout(data)
+	if err != nil {
+		return nil, err
+	}
+
+	impl, err := DetectImplementation(layout.decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	offline, _, err := layout.offlineSignature(impl)
+	return offline, err
+}
+
+// offlineSignature parses the offline signature block in impl's layout and returns it with its offset
+func (l *keyFileLayout) offlineSignature(impl string) (*OfflineSignature, int, error) {
+	decoded := l.decoded
+	signingKeyOffset := l.destLength + l.encryptionPrivateKeyLength(impl)
+	offset := signingKeyOffset + l.sigInfo.privateKeyLength
+	if len(decoded) <= offset {
+		return nil, 0, ErrNoOfflineSignature
+	}
+
+	// An offline key file stores zeros where the signing private key would be
+	signingKey := decoded[signingKeyOffset:offset]
+	if !bytes.Equal(signingKey, make([]byte, len(signingKey))) {
+		return nil, 0, ErrNoOfflineSignature
+	}
+
+	block := decoded[offset:]
+	if len(block) < offlineHeaderLength {
+		return nil, 0, fmt.Errorf("%w: offline signature block is %d bytes, need at least %d", ErrKeyTooShort, len(block), offlineHeaderLength)
+	}
+
+	expires := binary.BigEndian.Uint32(block[0:4])
+	transientType := binary.BigEndian.Uint16(block[4:6])
+	transientInfo, err := lookupSigningKeyType(transientType)
+	if err != nil {
+		return nil, 0, fmt.Errorf("offline signature: %w", err)
+	}
+
+	// The signature is made by the destination's own signing key
+	keyEnd := offlineHeaderLength + transientInfo.publicKeyLength
+	signatureEnd := keyEnd + l.sigInfo.signatureLength
+	if len(block) < signatureEnd {
+		return nil, 0, fmt.Errorf("%w: offline signature block is %d bytes, need %d for a %s transient key and %s signature",
+			ErrKeyTooShort, len(block), signatureEnd, transientInfo.name, l.sigInfo.name)
+	}
+
+	offline := &OfflineSignature{
+		Expires:            time.Unix(int64(expires), 0).UTC(),
+		TransientSigType:   transientType,
+		TransientKeyType:   transientInfo.name,
+		TransientPublicKey: block[offlineHeaderLength:keyEnd],
+		Signature:          block[keyEnd:signatureEnd],
+	}
+	if privateEnd := signatureEnd + transientInfo.privateKeyLength; len(block) >= privateEnd {
+		offline.TransientPrivateKey = block[signatureEnd:privateEnd]
+	}
+	return offline, offset, nil
+}
+
+// end returns the offset just past an offline signature block that starts at offset
+func (o *OfflineSignature) end(offset int) int {
+	return offset + offlineHeaderLength + len(o.TransientPublicKey) + len(o.Signature) + len(o.TransientPrivateKey)
+}
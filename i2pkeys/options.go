@@ -0,0 +1,226 @@
+package i2pkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Default permissions keep both the key and the directory listing private to the owner
+const (
+	DefaultDirPerm  os.FileMode = 0700
+	DefaultFilePerm os.FileMode = 0600
+)
+
+// Options controls how converted key files are written. The zero value uses the defaults.
+type Options struct {
+	DirPerm  os.FileMode // Mode for output directories that have to be created
+	FilePerm os.FileMode // Mode for a newly created output file
+	Gzip     bool        // Compress the output, which also happens for paths ending in .gz
+
+	// Validate checks each converted key with ValidateConverted before it is written. Off by
+	// default, as conversion already round-trips the output and batch users may not want the cost.
+	Validate bool
+
+	// InputFormat declares the encoding of the input, one of the Input constants.
+	// The default, InputAuto, detects it.
+	InputFormat string
+}
+
+// dirPerm returns the directory mode to use, falling back to DefaultDirPerm
+func (o Options) dirPerm() os.FileMode {
+	if o.DirPerm == 0 {
+		return DefaultDirPerm
+	}
+	return o.DirPerm
+}
+
+// filePerm returns the file mode to use, falling back to DefaultFilePerm
+func (o Options) filePerm() os.FileMode {
+	if o.FilePerm == 0 {
+		return DefaultFilePerm
+	}
+	return o.FilePerm
+}
+
+// ConvertKeyFileWithOptions is ConvertKeyFileContext with control over how the output is written
+func ConvertKeyFileWithOptions(ctx context.Context, inputPath, outputPath string, opts Options) error {
+	_, err := convertKeyFile(ctx, inputPath, outputPath, opts)
+	return err
+}
+
+// ConvertAndReturn is ConvertKeyFile that also returns the formatted key it wrote, uncompressed,
+// so the caller can check or use it without reading the output file back
+func ConvertAndReturn(inputPath, outputPath string) ([]byte, error) {
+	return convertKeyFile(context.Background(), inputPath, outputPath, Options{})
+}
+
+// convertKeyFile converts the key file at inputPath, writes it to outputPath and returns the formatted key
+func convertKeyFile(ctx context.Context, inputPath, outputPath string, opts Options) ([]byte, error) {
+	// Read the key file as binary data
+	data, err := readFileContext(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	// Key archives may be gzip-compressed
+	data, err = DecompressKeyData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", inputPath, err)
+	}
+
+	// Name the file, as the error alone doesn't say which of many keys failed
+	formattedOutput, err := ConvertKeysAs(data, opts.InputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", inputPath, err)
+	}
+
+	if opts.Validate {
+		if err := ValidateConverted(formattedOutput); err != nil {
+			return nil, fmt.Errorf("%s: %w", inputPath, err)
+		}
+	}
+
+	// Don't start writing once the caller has given up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := WriteKeyFile(outputPath, formattedOutput, opts); err != nil {
+		return nil, err
+	}
+	return formattedOutput, nil
+}
+
+// WriteKeyFile writes key data to outputPath, creating its directory if needed
+func WriteKeyFile(outputPath string, data []byte, opts Options) error {
+	if opts.Gzip || strings.HasSuffix(outputPath, ".gz") {
+		compressed, err := CompressKeyData(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+	}
+
+	// Create output directory if needed
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, opts.dirPerm()); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Writing through a symbolic link replaces the file it points to, not the link
+	outputPath, err := resolveOutputLink(outputPath)
+	if err != nil {
+		return err
+	}
+
+	// Devices and pipes can't be renamed over, so they are written in place
+	existing, err := os.Stat(outputPath)
+	if err == nil && !existing.Mode().IsRegular() {
+		return writeInPlace(outputPath, data)
+	}
+	if err != nil {
+		existing = nil
+	}
+
+	return replaceKeyFile(outputPath, data, opts.filePerm(), existing)
+}
+
+// Most symbolic links resolveOutputLink follows before giving up on a loop, as many as Linux allows
+const maxOutputLinks = 40
+
+// resolveOutputLink returns the file a symbolic link at outputPath points to, following links
+// to links, or outputPath itself when it is not a link. The file at the end may not exist yet,
+// so a dangling link names where the key is to be created.
+func resolveOutputLink(outputPath string) (string, error) {
+	path := outputPath
+	for range maxOutputLinks {
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check output path: %w", err)
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read output link: %w", err)
+		}
+		// A relative target is relative to the directory holding the link
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("output path '%s' goes through more than %d symbolic links", outputPath, maxOutputLinks)
+}
+
+// replaceKeyFile writes data to a temporary file beside outputPath and renames it into place,
+// so a crash or a full disk never leaves a truncated key behind. When the file being replaced
+// exists, the new one gets its mode and, where the platform allows, its owner; otherwise perm.
+func replaceKeyFile(outputPath string, data []byte, perm os.FileMode, existing os.FileInfo) error {
+	if existing != nil {
+		perm = existing.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary output file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Nothing may be left half-written, so any failure removes the temporary file
+	err = writeSynced(tmp, data)
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil && existing != nil {
+		err = preserveOwner(tmpPath, existing)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, outputPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// writeInPlace writes data to an existing named pipe or device. It is opened write-only and
+// neither created nor truncated, which a FIFO or a terminal has no use for, and not synced,
+// which pipes refuse.
+func writeInPlace(outputPath string, data []byte) error {
+	f, err := os.OpenFile(outputPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// writeSynced writes data to f, flushes it to disk and closes it
+func writeSynced(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
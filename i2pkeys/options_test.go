@@ -0,0 +1,47 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A symbolic link to a file that doesn't exist yet is written through, creating the file and
+// keeping the link
+func TestWriteKeyFileDanglingLink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "store", "key.dat")
+	if err := os.Mkdir(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "key.link")
+	if err := os.Symlink(filepath.Join("store", "key.dat"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("key data\n")
+	if err := WriteKeyFile(link, data, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(target); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("target holds %q (%v), want %q", got, err, data)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link was replaced: %v", err)
+	}
+}
+
+func TestWriteKeyFileLinkLoop(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := os.Symlink("b", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKeyFile(a, []byte("key data\n"), Options{}); err == nil {
+		t.Error("wrote through a loop of symbolic links")
+	}
+}
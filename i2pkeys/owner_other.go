@@ -0,0 +1,10 @@
+//go:build !unix
+
+package i2pkeys
+
+import "os"
+
+// preserveOwner is a no-op where files have no Unix owner to restore
+func preserveOwner(path string, existing os.FileInfo) error {
+	return nil
+}
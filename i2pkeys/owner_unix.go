@@ -0,0 +1,23 @@
+//go:build unix
+
+package i2pkeys
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives path the uid and gid recorded in existing. Only root can hand a file to
+// another user, so a refused chown leaves the file owned by whoever wrote it.
+func preserveOwner(path string, existing os.FileInfo) error {
+	stat, ok := existing.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if err := os.Lchown(path, int(stat.Uid), int(stat.Gid)); err != nil && !errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("failed to restore output file owner: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package i2pkeys
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+)
+
+// PEM block types used for I2P keys. The block contents are the raw key bytes,
+// so the standard Base64 of PEM is used rather than I2P Base64.
+const (
+	PEMTypeDestination = "I2P DESTINATION"
+	PEMTypePrivateKey  = "I2P PRIVATE KEY"
+)
+
+// ToPEM encodes a key pair as a destination PEM block followed, when the key pair holds
+// private keys, by a private key block containing the full keypair
+func ToPEM(kp *KeyPair) ([]byte, error) {
+	if len(kp.PublicKey) == 0 {
+		return nil, fmt.Errorf("%w: key pair has no destination", ErrKeyTooShort)
+	}
+
+	var out bytes.Buffer
+	if err := pem.Encode(&out, &pem.Block{Type: PEMTypeDestination, Bytes: kp.PublicKey}); err != nil {
+		return nil, fmt.Errorf("failed to encode destination: %w", err)
+	}
+	if len(kp.PrivateKey) > 0 {
+		if err := pem.Encode(&out, &pem.Block{Type: PEMTypePrivateKey, Bytes: kp.FullData}); err != nil {
+			return nil, fmt.Errorf("failed to encode private key: %w", err)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// FromPEM parses the blocks written by ToPEM. The private key block is used when present,
+// otherwise the destination block on its own.
+func FromPEM(data []byte) (*KeyPair, error) {
+	decoded, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyPair(decoded)
+}
+
+// isPEM reports whether data starts with an I2P PEM block
+func isPEM(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN I2P "))
+}
+
+// decodePEM returns the raw key bytes from I2P PEM blocks, checking that the blocks agree
+func decodePEM(data []byte) ([]byte, error) {
+	var destination, fullKey []byte
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case PEMTypeDestination:
+			destination = block.Bytes
+		case PEMTypePrivateKey:
+			fullKey = block.Bytes
+		}
+	}
+
+	switch {
+	case fullKey != nil:
+		if destination != nil && !bytes.HasPrefix(fullKey, destination) {
+			return nil, fmt.Errorf("%w: destination block does not match the private key block", ErrInvalidPEM)
+		}
+		return fullKey, nil
+	case destination != nil:
+		return destination, nil
+	default:
+		return nil, fmt.Errorf("%w: no %s or %s block found", ErrInvalidPEM, PEMTypePrivateKey, PEMTypeDestination)
+	}
+}
@@ -0,0 +1,169 @@
+package i2pkeys
+
+import "fmt"
+
+// Names of the certificate types defined by the I2P common structures spec
+var certificateTypeNames = map[byte]string{
+	certTypeNull:     "NULL",
+	certTypeHashcash: "HASHCASH",
+	certTypeHidden:   "HIDDEN",
+	certTypeSigned:   "SIGNED",
+	certTypeMultiple: "MULTIPLE",
+	certTypeKey:      "KEY",
+}
+
+// KeyComponent locates one part of a key blob
+type KeyComponent struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// KeyReport is everything InspectKey could learn about a key blob. Fields that depend
+// on the certificate's key types are left empty when the types are not known.
+type KeyReport struct {
+	DestinationLength  int            `json:"destination_length"`
+	FullKeyLength      int            `json:"full_key_length"`
+	CertificateType    string         `json:"certificate_type"`
+	CertificatePayload []byte         `json:"certificate_payload"`
+	SigningKeyType     string         `json:"signing_key_type,omitempty"`
+	EncryptionKeyType  string         `json:"encryption_key_type,omitempty"`
+	Base32Address      string         `json:"base32_address"`
+	HasPrivateKey      bool           `json:"has_private_key"`
+	Implementation     string         `json:"implementation,omitempty"`
+	Components         []KeyComponent `json:"components,omitempty"`
+	Hostname           string         `json:"hostname,omitempty"`
+	KeyTypeError       string         `json:"key_type_error,omitempty"`
+
+	// Set when the private keys are followed by an offline signature block
+	OfflineSignature *OfflineSignature `json:"offline_signature,omitempty"`
+}
+
+// InspectKey decodes a key blob in any supported form and reports on its structure
+func InspectKey(data []byte) (*KeyReport, error) {
+	keyPair, err := ParseKeyPair(data)
+	if err != nil {
+		return nil, err
+	}
+	decoded, destLength := keyPair.FullData, len(keyPair.PublicKey)
+
+	address, err := Base32Address(keyPair.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := ParseCertificate(decoded)
+	if err != nil {
+		return nil, err
+	}
+	report := &KeyReport{
+		DestinationLength:  destLength,
+		FullKeyLength:      len(decoded),
+		CertificateType:    cert.TypeName(),
+		CertificatePayload: cert.Payload,
+		Base32Address:      address,
+		HasPrivateKey:      len(keyPair.PrivateKey) > 0,
+		Hostname:           keyPair.Hostname,
+	}
+
+	// Without known key types only the certificate layout can be described
+	layout, err := newKeyFileLayout(decoded)
+	if err != nil {
+		report.KeyTypeError = err.Error()
+		report.Components = []KeyComponent{
+			{"public key slots", 0, certificateOffset},
+			{"certificate", certificateOffset, destLength - certificateOffset},
+		}
+		if report.HasPrivateKey {
+			report.Components = append(report.Components, KeyComponent{"private keys", destLength, len(decoded) - destLength})
+		}
+		return report, nil
+	}
+
+	report.SigningKeyType = layout.sigInfo.name
+	report.EncryptionKeyType = layout.cryptoInfo.name
+	report.Implementation = ImplJava
+	if report.HasPrivateKey {
+		if impl, err := DetectImplementation(decoded); err == nil {
+			report.Implementation = impl
+		}
+	}
+	if offline, _, err := layout.offlineSignature(report.Implementation); err == nil {
+		report.OfflineSignature = offline
+	}
+	report.Components = layout.components(report.Implementation)
+	return report, nil
+}
+
+// certificateTypeName returns the spec name of a certificate type
+func certificateTypeName(certType byte) string {
+	if name, ok := certificateTypeNames[certType]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", certType)
+}
+
+// components lists where each key and padding area sits in the key blob, in impl's layout.
+// The keys are the ones fillComponents picks out, so the table matches ParseKeysFor.
+func (l *keyFileLayout) components(impl string) []KeyComponent {
+	var keys KeyPair
+	l.fillComponents(&keys, impl)
+
+	// The crypto key is at the start of its slot and the signing key at the end of its own
+	cryptoLength := len(keys.EncryptionPublicKey)
+	components := []KeyComponent{{"encryption public key", 0, cryptoLength}}
+	sigStart := l.signingKeyStart()
+	if padding := sigStart - cryptoLength; padding > 0 {
+		components = append(components, KeyComponent{"padding", cryptoLength, padding})
+	}
+	components = append(components,
+		KeyComponent{"signing public key", sigStart, certificateOffset - sigStart},
+		KeyComponent{"certificate", certificateOffset, l.destLength - certificateOffset})
+	if excess := len(keys.SigningPublicKey) - (certificateOffset - sigStart); excess > 0 {
+		components = append(components, KeyComponent{"signing public key (excess, in certificate)", destinationLength + 4, excess})
+	}
+
+	encryptionOffset, signingOffset, ok := l.privateKeyOffsets(impl)
+	if !ok {
+		if l.destLength < len(l.decoded) {
+			components = append(components, KeyComponent{"incomplete private keys", l.destLength, len(l.decoded) - l.destLength})
+		}
+		return components
+	}
+	encryptionEnd := encryptionOffset + len(keys.EncryptionPrivateKey)
+	components = append(components, KeyComponent{"encryption private key", encryptionOffset, len(keys.EncryptionPrivateKey)})
+	if padding := signingOffset - encryptionEnd; padding > 0 {
+		components = append(components, KeyComponent{"padding", encryptionEnd, padding})
+	}
+	offline, blockOffset, offlineErr := l.offlineSignature(impl)
+	signingKeyName := "signing private key"
+	if offlineErr == nil {
+		signingKeyName += " (zeroed, the key is offline)"
+	}
+	components = append(components, KeyComponent{signingKeyName, signingOffset, len(keys.SigningPrivateKey)})
+	offset := signingOffset + len(keys.SigningPrivateKey)
+
+	// Break an offline signature block down into its fields
+	if offlineErr == nil {
+		components = append(components, KeyComponent{"offline signature expiry and transient type", blockOffset, offlineHeaderLength})
+		offset = blockOffset + offlineHeaderLength
+		for _, part := range []struct {
+			name string
+			key  []byte
+		}{
+			{"transient signing public key", offline.TransientPublicKey},
+			{"offline signature", offline.Signature},
+			{"transient signing private key", offline.TransientPrivateKey},
+		} {
+			if len(part.key) > 0 {
+				components = append(components, KeyComponent{part.name, offset, len(part.key)})
+				offset += len(part.key)
+			}
+		}
+	}
+
+	if offset < len(l.decoded) {
+		components = append(components, KeyComponent{"trailing data", offset, len(l.decoded) - offset})
+	}
+	return components
+}
@@ -0,0 +1,52 @@
+package i2pkeys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatSAMKeys converts the single-line destination and keys a SAM bridge returns,
+// such as "DEST REPLY PUB=... PRIV=..." or "SESSION STATUS RESULT=OK DESTINATION=...",
+// into the two-line format. A bare Base64 keypair is accepted as well.
+func FormatSAMKeys(samDest string) ([]byte, error) {
+	key, err := samKeyField(samDest)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := fromI2PBase64(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+	}
+
+	formattedOutput, err := formatKeyPair(decoded)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(formattedOutput), nil
+}
+
+// samKeyField picks the private keys out of a SAM reply line
+func samKeyField(samDest string) (string, error) {
+	fields := strings.Fields(samDest)
+
+	// The keys are a named value in session and DEST GENERATE replies
+	for _, field := range fields {
+		name, value, found := strings.Cut(field, "=")
+		if found && (name == "DESTINATION" || name == "PRIV") {
+			return value, nil
+		}
+	}
+
+	// Otherwise look for a bare key, skipping words such as DEST that happen to be valid Base64
+	for _, field := range fields {
+		if !isI2PBase64Format(field) {
+			continue
+		}
+		if decoded, err := fromI2PBase64(field); err == nil && len(decoded) >= destinationLength {
+			return field, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no keys found in SAM reply", ErrInvalidBase64)
+}
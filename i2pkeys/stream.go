@@ -0,0 +1,37 @@
+package i2pkeys
+
+import (
+	"fmt"
+	"io"
+)
+
+// ConvertStream reads key data from r and writes the two-line format to w
+func ConvertStream(r io.Reader, w io.Writer) error {
+	_, _, err := ConvertStreamN(r, w)
+	return err
+}
+
+// ConvertStreamN is ConvertStream that also returns how many bytes were read from r and
+// written to w, so callers can confirm the whole input was consumed. The counts are valid
+// even when err is not nil.
+func ConvertStreamN(r io.Reader, w io.Writer) (inN, outN int64, err error) {
+	// Keys are small, so buffering the whole input is fine
+	data, err := io.ReadAll(r)
+	inN = int64(len(data))
+	if err != nil {
+		return inN, 0, fmt.Errorf("failed to read key data: %w", err)
+	}
+
+	formattedOutput, err := ConvertKeys(data)
+	if err != nil {
+		return inN, 0, err
+	}
+
+	n, err := w.Write(formattedOutput)
+	outN = int64(n)
+	if err != nil {
+		return inN, outN, fmt.Errorf("failed to write formatted key: %w", err)
+	}
+
+	return inN, outN, nil
+}
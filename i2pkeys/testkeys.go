@@ -0,0 +1,56 @@
+package i2pkeys
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// GenerateTestKeyPair builds a random, structurally valid binary keypair in the Java I2P
+// layout for the given signing key type, with ElGamal encryption keys. The keys are not
+// real key pairs, so the result is only useful for exercising the parser and converter.
+func GenerateTestKeyPair(sigType int) ([]byte, error) {
+	if sigType < 0 || sigType > 0xffff {
+		return nil, fmt.Errorf("%w: unknown signing key type %d", ErrUnsupportedKeyType, sigType)
+	}
+	sigInfo, err := lookupSigningKeyType(uint16(sigType))
+	if err != nil {
+		return nil, err
+	}
+	cryptoInfo, err := lookupCryptoKeyType(cryptoTypeElGamal)
+	if err != nil {
+		return nil, err
+	}
+
+	// DSA-SHA1 with ElGamal is the default pairing and uses a NULL certificate
+	var certificate []byte
+	if sigType == sigTypeDSASHA1 {
+		certificate = []byte{certTypeNull, 0, 0}
+	} else {
+		// Signing key bytes that don't fit the 128-byte slot go in the KEY certificate
+		excess := max(sigInfo.publicKeyLength-signingKeySlotLength, 0)
+		payload := make([]byte, 4+excess)
+		binary.BigEndian.PutUint16(payload[0:2], uint16(sigType))
+		binary.BigEndian.PutUint16(payload[2:4], cryptoTypeElGamal)
+		if _, err := rand.Read(payload[4:]); err != nil {
+			return nil, fmt.Errorf("failed to generate key data: %w", err)
+		}
+
+		certificate = make([]byte, certificateHeaderLength, certificateHeaderLength+len(payload))
+		certificate[0] = certTypeKey
+		binary.BigEndian.PutUint16(certificate[1:3], uint16(len(payload)))
+		certificate = append(certificate, payload...)
+	}
+
+	// Both public key slots and the private keys are filled with random bytes
+	keys := make([]byte, certificateOffset+cryptoInfo.privateKeyLength+sigInfo.privateKeyLength)
+	if _, err := rand.Read(keys); err != nil {
+		return nil, fmt.Errorf("failed to generate key data: %w", err)
+	}
+
+	keyPair := make([]byte, 0, len(keys)+len(certificate))
+	keyPair = append(keyPair, keys[:certificateOffset]...)
+	keyPair = append(keyPair, certificate...)
+	keyPair = append(keyPair, keys[certificateOffset:]...)
+	return keyPair, nil
+}
@@ -0,0 +1,57 @@
+package i2pkeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// StandardToI2PBase64 rewrites standard Base64 text, padded or not, in I2P's alphabet
+func StandardToI2PBase64(s string) (string, error) {
+	decoded, err := decodeBase64(base64.StdEncoding, s)
+	if err != nil {
+		return "", fmt.Errorf("%w: standard Base64: %v", ErrInvalidBase64, err)
+	}
+	return toI2PBase64(decoded), nil
+}
+
+// I2PToStandardBase64 rewrites I2P Base64 text, padded or not, in the standard alphabet
+func I2PToStandardBase64(s string) (string, error) {
+	decoded, err := decodeBase64(i2pEncoding(), s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+	}
+	return base64.StdEncoding.EncodeToString(decoded), nil
+}
+
+// TranscodeLines converts each key line of data to the other alphabet. Lines using '+' or '/'
+// are taken as standard Base64 and the rest as I2P Base64; blank lines are dropped.
+func TranscodeLines(data []byte) ([]byte, error) {
+	lines := keyLines(string(data))
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no Base64 lines to transcode", ErrInvalidBase64)
+	}
+
+	transcoded := make([]string, 0, len(lines))
+	for i, line := range lines {
+		convert := I2PToStandardBase64
+		if strings.ContainsAny(line, "+/") {
+			convert = StandardToI2PBase64
+		}
+		out, err := convert(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		transcoded = append(transcoded, out)
+	}
+	return []byte(strings.Join(transcoded, "\n") + "\n"), nil
+}
+
+// decodeBase64 decodes s under enc, also accepting it without padding
+func decodeBase64(enc *base64.Encoding, s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "=") {
+		return enc.DecodeString(s)
+	}
+	return enc.WithPadding(base64.NoPadding).DecodeString(s)
+}
@@ -0,0 +1,112 @@
+package i2pkeys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationResult is the outcome of one integrity check
+type ValidationResult struct {
+	Check string // Description of what was checked
+	Err   error  // Why the check failed, nil when it passed
+}
+
+// Passed reports whether the check succeeded
+func (r ValidationResult) Passed() bool {
+	return r.Err == nil
+}
+
+// ValidateKeys runs every integrity check on two-line key data. Checks that depend
+// on an earlier failed check are not run, so the last result explains why it stopped.
+func ValidateKeys(data []byte) []ValidationResult {
+	var results []ValidationResult
+	check := func(name string, err error) bool {
+		results = append(results, ValidationResult{Check: name, Err: err})
+		return err == nil
+	}
+
+	// Everything else needs the two lines
+	var formatErr error
+	if !IsCorrectFormat(string(data)) {
+		formatErr = ErrInvalidFormat
+	}
+	if !check("two-line format", formatErr) {
+		return results
+	}
+	lines := keyLines(string(data))
+
+	destination, err := fromI2PBase64(strings.TrimSpace(lines[0]))
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+	}
+	if !check("destination line decodes", err) {
+		return results
+	}
+
+	fullKey, err := fromI2PBase64(strings.TrimSpace(lines[1]))
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+	}
+	if !check("full key line decodes", err) {
+		return results
+	}
+
+	// Line 1 must be exactly the leading bytes of line 2
+	check("destination is a prefix of the full key", checkDestinationPrefix(destination, fullKey))
+
+	if !check("certificate is well formed", ValidateDestination(destination)) {
+		return results
+	}
+	check("destination length is canonical", CheckDestinationLength(destination))
+
+	address, err := Base32Address(destination)
+	if err == nil && len(address) != base32HashLength+len(base32AddressSuffix) {
+		err = fmt.Errorf("unexpected base32 address %q", address)
+	}
+	check("destination hashes to a base32 address", err)
+
+	return results
+}
+
+// ValidateConverted runs the checks of Options.Validate on formatted key data, a two-line key
+// or several in a row: each certificate must parse, the destination and private keys must be
+// the lengths the key types call for, and line 1 must be the start of line 2. Keys of types the
+// package doesn't know pass the length checks, as there is nothing to measure them against.
+func ValidateConverted(formatted []byte) error {
+	lines := keyLines(string(formatted))
+	if len(lines) == 0 || len(lines)%2 != 0 {
+		return fmt.Errorf("%w: found %d lines", ErrInvalidFormat, len(lines))
+	}
+
+	for i := 0; i < len(lines); i += 2 {
+		err := validateConvertedKey(lines[i] + "\n" + lines[i+1])
+		if err != nil && len(lines) > 2 {
+			return fmt.Errorf("key %d: %w", i/2+1, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateConvertedKey checks a single two-line key for ValidateConverted
+func validateConvertedKey(key string) error {
+	// Parses the certificate and checks the prefix
+	if err := ValidateFormat([]byte(key)); err != nil {
+		return err
+	}
+
+	destination, fullKey, err := decodeKeyLines(key)
+	if err != nil {
+		return err
+	}
+	if err := CheckDestinationLength(destination); err != nil {
+		return err
+	}
+	if err := VerifyKeyLength(fullKey); err != nil && !errors.Is(err, ErrUnsupportedKeyType) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package i2pkeys
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalkOptions controls which files WalkKeyFiles visits
+type WalkOptions struct {
+	Recursive   bool // Descend into subdirectories
+	FollowLinks bool // Follow symbolic links to directories and to files outside the tree
+}
+
+// WalkKeyFiles calls visit for every file in dir, descending into subdirectories when Recursive is set.
+// Entries that can't be read are passed to visit with their error, and symbolic links that are not
+// followed with an error wrapping ErrLinkSkipped. It stops between files once ctx is done.
+func WalkKeyFiles(ctx context.Context, dir string, opts WalkOptions, visit func(path string, err error)) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	w := &walker{ctx: ctx, root: root, opts: opts, visit: visit, seen: map[string]bool{root: true}}
+	if !opts.Recursive {
+		return w.readDir(dir)
+	}
+	return w.walkDir(dir)
+}
+
+// walker holds the state of one WalkKeyFiles run
+type walker struct {
+	ctx   context.Context
+	root  string // The walked directory with symbolic links resolved
+	opts  WalkOptions
+	visit func(path string, err error)
+	seen  map[string]bool // Resolved directories already walked, so link loops end
+}
+
+// readDir visits the files directly inside dir
+func (w *walker) readDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			continue
+		case entry.Type()&fs.ModeSymlink != 0:
+			w.link(path, false)
+		default:
+			w.visit(path, nil)
+		}
+	}
+	return nil
+}
+
+// walkDir visits every file below dir
+func (w *walker) walkDir(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := w.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// The root itself must be readable, anything below it is reported and skipped
+			if path == dir {
+				return err
+			}
+			w.visit(path, err)
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			return w.link(path, true)
+		default:
+			w.visit(path, nil)
+			return nil
+		}
+	})
+	if err != nil {
+		if w.ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return nil
+}
+
+// link handles a symbolic link found while walking. Links to files inside the tree are visited,
+// anything else only with FollowLinks, and directories only when walking recursively.
+func (w *walker) link(path string, recursive bool) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		w.visit(path, err)
+		return nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		w.visit(path, err)
+		return nil
+	}
+
+	if !info.IsDir() {
+		if !w.opts.FollowLinks && !withinDir(w.root, target) {
+			w.visit(path, fmt.Errorf("%w: it points outside the directory", ErrLinkSkipped))
+			return nil
+		}
+		w.visit(path, nil)
+		return nil
+	}
+
+	if !recursive {
+		return nil
+	}
+	if !w.opts.FollowLinks {
+		w.visit(path, fmt.Errorf("%w: it points to a directory", ErrLinkSkipped))
+		return nil
+	}
+	if w.seen[target] {
+		w.visit(path, fmt.Errorf("%w: directory already walked", ErrLinkSkipped))
+		return nil
+	}
+	w.seen[target] = true
+
+	// The trailing separator makes WalkDir resolve the link instead of reporting it
+	if err := w.walkDir(path + string(filepath.Separator)); err != nil && w.ctx.Err() != nil {
+		return err
+	}
+	return nil
+}
+
+// withinDir reports whether path is dir itself or somewhere below it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
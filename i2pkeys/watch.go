@@ -0,0 +1,146 @@
+package i2pkeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often WatchDir scans when WatchOptions.Interval is zero
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchOptions controls WatchDir
+type WatchOptions struct {
+	Options             // How each output file is written
+	Walk    WalkOptions // Which files are watched
+
+	// OutputDir receives the converted keys, each at the path its input has below the watched directory
+	OutputDir string
+
+	// Interval is the time between scans. A file must keep its size and modification time for
+	// one interval before it is converted, so writers that pause for longer need a longer one.
+	Interval time.Duration
+
+	// Progress, when set, receives the result of each file as it is converted or fails
+	Progress func(FileResult)
+}
+
+// WatchDir scans dir every interval and converts key files into OutputDir as they appear or
+// change. A file is converted once a scan finds the same size and modification time as the
+// scan before, so a key still being written is left until it is complete; a failed file is
+// tried again only after it changes. Inputs whose output is at least as new, as after a
+// restart, are reported up to date. Names starting with a dot, which partial downloads and
+// editors use for files in progress, are ignored, as is OutputDir when it is inside dir.
+// WatchDir runs until ctx is done and returns ctx's error, or an error if dir can't be read.
+func WatchDir(ctx context.Context, dir string, opts WatchOptions) error {
+	if opts.OutputDir == "" {
+		return errors.New("no output directory to watch into")
+	}
+	outputRoot, err := filepath.Abs(opts.OutputDir)
+	if err != nil {
+		return err
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &dirWatch{dir: dir, outputRoot: outputRoot, opts: opts, files: map[string]watchedFile{}}
+	// Every file is converted like a ConvertDir file with SkipExisting, into OutputDir
+	w.run = &dirRun{
+		root:          opts.OutputDir,
+		opts:          DirOptions{Options: opts.Options, SkipExisting: true, OutputPath: w.outputPath},
+		copyFormatted: true,
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// dirWatch is the state of one WatchDir call, carried from scan to scan
+type dirWatch struct {
+	dir        string
+	outputRoot string // OutputDir as an absolute path
+	opts       WatchOptions
+	files      map[string]watchedFile
+	run        *dirRun // Converts each file
+}
+
+// watchedFile is what the last scan found of a file
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	handled bool // Converted or failed at this size and modification time
+}
+
+// scan looks at every file once, converting the ones that have stopped changing
+func (w *dirWatch) scan(ctx context.Context) error {
+	seen := make(map[string]bool)
+	err := WalkKeyFiles(ctx, w.dir, w.opts.Walk, func(path string, err error) {
+		// Unreadable entries and skipped links are looked at again on the next scan
+		if err != nil || strings.HasPrefix(filepath.Base(path), ".") {
+			return
+		}
+		if abs, err := filepath.Abs(path); err != nil || withinDir(w.outputRoot, abs) {
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		seen[path] = true
+
+		current := watchedFile{size: info.Size(), modTime: info.ModTime()}
+		last, known := w.files[path]
+		if !known || last.size != current.size || !last.modTime.Equal(current.modTime) {
+			// New or still being written
+			w.files[path] = current
+			return
+		}
+		if last.handled {
+			return
+		}
+
+		result := w.run.convert(ctx, path)
+		if result.Status == "" {
+			// Cancelled before anything was written
+			return
+		}
+		current.handled = true
+		w.files[path] = current
+		if w.opts.Progress != nil {
+			w.opts.Progress(result)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Forget deleted files, so one put back under the same name is converted again
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+		}
+	}
+	return nil
+}
+
+// outputPath places the output of a file at the path the file has below the watched directory
+func (w *dirWatch) outputPath(path string) string {
+	// WalkKeyFiles only yields paths below w.dir, so Rel can't fail
+	rel, _ := filepath.Rel(w.dir, path)
+	return filepath.Join(w.opts.OutputDir, rel)
+}
@@ -0,0 +1,113 @@
+package i2pkeys
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Zip archives start with the signature of their first local file header
+var zipMagic = []byte("PK\x03\x04")
+
+// IsZip reports whether data is a zip archive
+func IsZip(data []byte) bool {
+	return bytes.HasPrefix(data, zipMagic)
+}
+
+// ConvertZip converts every key file in the zip archive data and passes each to write under
+// its entry name, directories included, for the caller to store in a directory or a new archive.
+// Entries already in the correct format are passed on unchanged, so the output holds every key
+// of the archive. Entries that don't convert, such as a README next to the keys, are reported as
+// StatusNotKey and left out. Entries that can't be read, whose names would escape the archive's
+// root, or that write returns an error for are reported as failed without stopping the rest.
+// Entries are converted like ConvertDir's files: opts.InputFormat declares their encoding, and
+// with opts.Validate set a key that fails ValidateConverted is reported as failed. The rest of
+// opts is for writing files, which is up to write. The error return means the archive itself
+// could not be read.
+func ConvertZip(data []byte, opts Options, write func(name string, formatted []byte) error) (*DirReport, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	report := &DirReport{}
+	for _, entry := range archive.File {
+		if strings.HasSuffix(entry.Name, "/") {
+			continue
+		}
+
+		result := convertZipEntry(entry, opts)
+		if result.Status == StatusConverted || result.Status == StatusSkipped {
+			if err := write(entry.Name, result.formatted); err != nil {
+				result.FileResult = FileResult{Path: entry.Name, Status: StatusFailed, Err: err}
+			}
+		}
+
+		report.add(result.FileResult)
+	}
+	return report, nil
+}
+
+// zipEntryResult is the outcome for one entry together with the data to write for it
+type zipEntryResult struct {
+	FileResult
+	formatted []byte
+}
+
+// convertZipEntry reads and converts a single file of an archive
+func convertZipEntry(entry *zip.File, opts Options) zipEntryResult {
+	name := entry.Name
+	failed := func(err error) zipEntryResult {
+		return zipEntryResult{FileResult: FileResult{Path: name, Status: StatusFailed, Err: err}}
+	}
+
+	// Names like ../x or /etc/x would be written outside wherever the caller extracts to
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return failed(fmt.Errorf("entry name %q is not a relative path inside the archive", name))
+	}
+
+	data, err := readZipEntry(entry)
+	if err == nil {
+		data, err = DecompressKeyData(data)
+	}
+	if err != nil {
+		return failed(err)
+	}
+
+	// Bundles often carry notes or checksums beside the keys
+	formatted, err := ConvertKeysAs(data, opts.InputFormat)
+	if err != nil {
+		return zipEntryResult{FileResult: FileResult{Path: name, Status: StatusNotKey, Err: err}}
+	}
+
+	// A file that converts is a key, so one that then fails the checks is a broken key
+	if opts.Validate {
+		if err := ValidateConverted(formatted); err != nil {
+			return failed(err)
+		}
+	}
+
+	status := StatusConverted
+	reason := ""
+	if IsCorrectFormat(string(data)) && bytes.Equal(formatted, data) {
+		status, reason = StatusSkipped, "already in the correct format"
+	}
+	return zipEntryResult{FileResult: FileResult{Path: name, Status: status, Output: name, Reason: reason}, formatted: formatted}
+}
+
+// readZipEntry reads an entry of an archive, refusing ones too large to be key files
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip entry: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := readLimited(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip entry: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
+)
+
+// runCheck reports whether a key file is in the two-line format and returns the exit code
+func runCheck(inputFile string, strict bool) int {
+	data, err := loadInput(inputFile)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	switch err := checkKeyFormat(data, strict); {
+	case err == nil:
+		fmt.Println("File IS in the correct two-line format")
+		return exitOK
+	case errors.Is(err, i2pkeys.ErrInvalidFormat):
+		fmt.Println("File is NOT in the correct two-line format")
+		return exitFormat
+	case errors.Is(err, i2pkeys.ErrSwappedLines):
+		fmt.Println("File is NOT in the correct two-line format: its lines are swapped, -repair puts them in order")
+		return exitFormat
+	default:
+		fmt.Printf("File is in the two-line format but failed strict validation: %s\n", err)
+	}
+	return exitValidation
+}
+
+// runCheckDirectory checks every file in a directory, prints a tally and the files that failed,
+// and returns the exit code
+func runCheckDirectory(dir string, walk i2pkeys.WalkOptions, strict bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var total, correct int
+	var incorrect []string
+	err := i2pkeys.WalkKeyFiles(ctx, dir, walk, func(path string, err error) {
+		if errors.Is(err, i2pkeys.ErrLinkSkipped) {
+			return
+		}
+		total++
+		if err == nil {
+			var data []byte
+			if data, err = i2pkeys.ReadKeyFileContext(ctx, path); err == nil {
+				err = checkKeyFormat(data, strict)
+			}
+		}
+		if err != nil {
+			incorrect = append(incorrect, fmt.Sprintf("%s: %s", path, err))
+			return
+		}
+		correct++
+	})
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted {
+		return fail(os.Stderr, &ioError{err})
+	}
+
+	if len(incorrect) > 0 {
+		fmt.Println("Incorrect files:")
+		for _, line := range incorrect {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println()
+	}
+	if interrupted {
+		fmt.Println("Interrupted")
+	}
+	fmt.Printf("%d files, %d correct, %d incorrect\n", total, correct, len(incorrect))
+
+	if interrupted {
+		return exitFailure
+	}
+	if len(incorrect) > 0 {
+		return exitFormat
+	}
+	return exitOK
+}
+
+// checkKeyFormat returns nil when data is in the two-line format, and with strict set also uses
+// the I2P alphabet and holds a well-formed destination. Otherwise it returns why not.
+func checkKeyFormat(data []byte, strict bool) error {
+	if !i2pkeys.IsCorrectFormat(string(data)) {
+		if i2pkeys.LinesSwapped(data) {
+			return i2pkeys.ErrSwappedLines
+		}
+		return i2pkeys.ErrInvalidFormat
+	}
+
+	// Strict mode also checks the alphabet and destination structure
+	if strict {
+		if err := i2pkeys.CheckAlphabet(string(data)); err != nil {
+			return err
+		}
+		return i2pkeys.ValidateFormat(data)
+	}
+	return nil
+}
+
+// runValidate prints the outcome of every integrity check, and with allowed of the signing
+// key type policy, and returns the exit code
+func runValidate(inputFile string, allowed []uint16) int {
+	data, err := loadInput(inputFile)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	results := i2pkeys.ValidateKeys(data)
+	if allowed != nil && results[len(results)-1].Passed() {
+		destination, err := i2pkeys.ExtractDestination(data)
+		if err == nil {
+			err = i2pkeys.CheckSigningType(destination, allowed)
+		}
+		results = append(results, i2pkeys.ValidationResult{Check: "signing key type is allowed", Err: err})
+	}
+
+	failed := false
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Printf("PASS %s\n", result.Check)
+		} else {
+			fmt.Printf("FAIL %s: %s\n", result.Check, result.Err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return exitValidation
+	}
+	return exitOK
+}
+
+// runAddress prints the .b32.i2p address of a key, or with hexHash its destination hash in hex,
+// and returns the exit code
+func runAddress(inputFile string, hexHash bool) int {
+	data, err := loadInput(inputFile)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	keyPair, err := i2pkeys.ParseKeyPair(data)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	// Router consoles and logs show identity hashes as hex
+	if hexHash {
+		hash := i2pkeys.DestinationHash(keyPair.PublicKey)
+		fmt.Println(hex.EncodeToString(hash[:]))
+		return exitOK
+	}
+
+	address, err := i2pkeys.Base32Address(keyPair.PublicKey)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	fmt.Println(address)
+	return exitOK
+}
+
+// runInspect prints a report on the structure of a key without writing anything and returns the exit code
+func runInspect(inputFile string, jsonOutput bool) int {
+	data, err := loadInput(inputFile)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	report, err := i2pkeys.InspectKey(data)
+	if err != nil {
+		return fail(os.Stderr, err)
+	}
+
+	if jsonOutput {
+		description, err := json.Marshal(report)
+		if err != nil {
+			return fail(os.Stderr, err)
+		}
+		fmt.Println(string(description))
+		return exitOK
+	}
+
+	// Values line up after the longest label
+	field := func(label, format string, args ...any) {
+		fmt.Printf("%-21s"+format+"\n", append([]any{label + ":"}, args...)...)
+	}
+
+	field("Destination length", "%d bytes", report.DestinationLength)
+	field("Full key length", "%d bytes", report.FullKeyLength)
+	certificate := fmt.Sprintf("%s, %d bytes of payload", report.CertificateType, len(report.CertificatePayload))
+	if len(report.CertificatePayload) > 0 {
+		certificate += fmt.Sprintf(" (%s)", hex.EncodeToString(report.CertificatePayload))
+	}
+	field("Certificate", "%s", certificate)
+	if report.KeyTypeError != "" {
+		field("Key types", "%s", report.KeyTypeError)
+	} else {
+		field("Signing key type", "%s", report.SigningKeyType)
+		field("Encryption key type", "%s", report.EncryptionKeyType)
+	}
+	field("Base32 address", "%s", report.Base32Address)
+	if report.Hostname != "" {
+		field("Hostname", "%s", report.Hostname)
+	}
+	if report.HasPrivateKey && report.Implementation != "" {
+		field("Private key", "present (%s layout)", report.Implementation)
+	} else if report.HasPrivateKey {
+		field("Private key", "present")
+	} else {
+		field("Private key", "not present")
+	}
+
+	if offline := report.OfflineSignature; offline != nil {
+		field("Offline signature", "%s transient key, expires %s", offline.TransientKeyType, offline.Expires.Format(time.RFC3339))
+		if offline.TransientPrivateKey == nil {
+			field("", "transient private key not present")
+		}
+	}
+
+	fmt.Println("\nComponents:")
+	fmt.Printf("  %6s  %6s  %s\n", "offset", "length", "component")
+	for _, component := range report.Components {
+		fmt.Printf("  %6d  %6d  %s\n", component.Offset, component.Length, component.Name)
+	}
+	return exitOK
+}
+
+// runCompare prints which fields of two keys are equal and returns exitValidation when any differ.
+// Private keys are only compared, never printed.
+func runCompare(fileA, fileB string) int {
+	var reports [2]*i2pkeys.KeyReport
+	var keyPairs [2]*i2pkeys.KeyPair
+	for i, file := range []string{fileA, fileB} {
+		data, err := loadInput(file)
+		if err != nil {
+			return fail(os.Stderr, err)
+		}
+		if reports[i], err = i2pkeys.InspectKey(data); err != nil {
+			return fail(os.Stderr, fmt.Errorf("%s: %w", file, err))
+		}
+		if keyPairs[i], err = i2pkeys.ParseKeyPair(data); err != nil {
+			return fail(os.Stderr, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+
+	a, b := reports[0], reports[1]
+	hashA, hashB := i2pkeys.DestinationHash(keyPairs[0].PublicKey), i2pkeys.DestinationHash(keyPairs[1].PublicKey)
+	presence := func(present bool) string {
+		if present {
+			return "present"
+		}
+		return "not present"
+	}
+
+	differ := false
+	row := func(field string, equal bool, valueA, valueB string) {
+		result := "EQUAL"
+		if !equal {
+			result = "DIFFERENT"
+			differ = true
+		}
+		fmt.Printf("%-20s %-10s %s", field, result, valueA)
+		if valueA != valueB {
+			fmt.Printf(" / %s", valueB)
+		}
+		fmt.Println()
+	}
+
+	row("Destination hash", hashA == hashB, hex.EncodeToString(hashA[:]), hex.EncodeToString(hashB[:]))
+	row("Certificate type", a.CertificateType == b.CertificateType, a.CertificateType, b.CertificateType)
+	row("Signing key type", a.SigningKeyType == b.SigningKeyType, a.SigningKeyType, b.SigningKeyType)
+	row("Encryption key type", a.EncryptionKeyType == b.EncryptionKeyType, a.EncryptionKeyType, b.EncryptionKeyType)
+	privateA, privateB := presence(a.HasPrivateKey), presence(b.HasPrivateKey)
+	samePrivate := bytes.Equal(keyPairs[0].PrivateKey, keyPairs[1].PrivateKey)
+	if !samePrivate && a.HasPrivateKey && b.HasPrivateKey {
+		privateA, privateB = "present in both, not the same", "present in both, not the same"
+	}
+	row("Private key", samePrivate, privateA, privateB)
+
+	if differ {
+		return exitValidation
+	}
+	return exitOK
+}
@@ -1,119 +1,286 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-i2p/i2pkeys-converter/i2pkeys"
 )
 
 func main() {
+	// A known subcommand gets its own flags, anything else is the legacy flag interface
+	if len(os.Args) > 1 {
+		if os.Args[1] == "help" {
+			os.Exit(helpCommand(os.Args[2:]))
+		}
+		if cmd, ok := findCommand(os.Args[1]); ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	os.Exit(runLegacy(os.Args[1:]))
+}
+
+// runLegacy implements the original single-command flags, which predate the subcommands
+func runLegacy(args []string) int {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
 	// Command line arguments
-	inputFile := flag.String("in", "", "Path to the I2P key file (required)")
-	outputFile := flag.String("out", "", "Path to save the formatted key (optional)")
-	verbose := flag.Bool("v", false, "Verbose output with key details")
-	checkFormat := flag.Bool("check", false, "Check if a file is already in the correct format")
+	inputFile := fs.String("in", "", "Path to the I2P key file, or - for stdin (required)")
+	outputFile := fs.String("out", "", "Path to save the formatted key, or - for stdout (optional)")
+	verbose := fs.Bool("v", false, "Verbose output with key details")
+	checkFormat := fs.Bool("check", false, "Check if a file is already in the correct format")
+	strict := fs.Bool("strict", false, "Reject standard-Base64 keys and, with -check, validate the destination's certificate")
+	validate := fs.Bool("validate", false, "Decode the key and report on each integrity check")
+	showB32 := fs.Bool("b32", false, "Print the .b32.i2p address of the key")
+	showHash := fs.Bool("hash", false, "Print the SHA-256 destination hash of the key as hex")
+	reverse := fs.Bool("reverse", false, "Convert a two-line formatted key back to the raw binary keypair")
+	pubOnly := fs.Bool("pubonly", false, "Write only the public destination line to <input>.destination, without the private key")
+	impl := fs.String("impl", "", "Private key layout to expect: java or i2pd (default: autodetect)")
+	allKeys := fs.Bool("all", false, "Convert every key in a multi-key file, not just the first")
+	jsonOutput := fs.Bool("json", false, "Print a JSON description of the key instead of text")
+	outputFlags := addOutputFlags(fs)
+	batchDir := fs.String("dir", "", "Convert every key file in a directory")
+	recursive := fs.Bool("recursive", false, "Descend into subdirectories when using -dir")
+	follow := fs.Bool("follow", false, "Follow symbolic links out of the directory when using -dir")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "Number of files to convert at once when using -dir")
 
 	// Custom usage message
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "I2P Keys Converter - Format I2P keys for Go I2P libraries\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s -in keyfile [-out outputfile] [-v] [-check]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  Convert binary key file:   %s -in keys.dat -out keys.dat.formatted\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  Check key file format:     %s -in keys.dat -check\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  Format with verbose info:  %s -in keys.dat -v\n", os.Args[0])
+	fs.Usage = func() {
+		printUsage()
+		fmt.Fprintf(os.Stderr, "\nDeprecated flags, still accepted without a command:\n")
+		fmt.Fprintf(os.Stderr, "  %s -in keyfile [-out outputfile [-force]] [-n] [-q] [-v] [-check [-strict]] [-validate] [-b32] [-hash] [-reverse] [-json] [-all] [-pubonly] [-impl java|i2pd]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dir directory [-recursive [-follow]] [-jobs n] [-force] [-n] [-check [-strict]]\n\n", os.Args[0])
+		fs.PrintDefaults()
 	}
 
-	flag.Parse()
+	fs.Parse(args)
+
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: flags without a command are deprecated and will be removed, see '%s help'\n", os.Args[0])
+	}
+
+	output := outputFlags()
+	if *strict {
+		output.file.Validate = true
+	}
+	if output.quiet && (*verbose || *jsonOutput) {
+		fmt.Fprintln(os.Stderr, "Error: -q can't be used with -v or -json")
+		return exitUsage
+	}
+
+	// If a directory is given, check or convert every key file in it
+	walk := i2pkeys.WalkOptions{Recursive: *recursive, FollowLinks: *follow}
+	if *batchDir != "" && *checkFormat {
+		return runCheckDirectory(*batchDir, walk, *strict)
+	}
+	if *batchDir != "" {
+		return runBatch(*batchDir, batchOptions{walk: walk, output: output, jobs: *jobs, template: defaultOutputTemplate, jsonLines: *jsonOutput})
+	}
 
 	// Validate input file parameter
 	if *inputFile == "" {
-		fmt.Println("Error: Input file (-in) is required")
-		flag.Usage()
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Error: Input file (-in) is required")
+		fs.Usage()
+		return exitUsage
 	}
 
-	// Check if input file exists
-	if _, err := os.Stat(*inputFile); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file '%s' does not exist\n", *inputFile)
-		os.Exit(1)
+	// The old mode flags map onto the subcommands
+	switch {
+	case *checkFormat:
+		return runCheck(*inputFile, *strict)
+	case *validate:
+		return runValidate(*inputFile, nil)
+	case *showB32:
+		return runAddress(*inputFile, false)
+	case *showHash:
+		return runAddress(*inputFile, true)
+	case *reverse:
+		return runExport(*inputFile, *outputFile, reverseExport, output)
+	case *pubOnly:
+		return runExport(*inputFile, *outputFile, pubOnlyExport, output)
+	default:
+		return runConvert(convertConfig{
+			inputFile:  *inputFile,
+			outputFile: *outputFile,
+			verbose:    *verbose,
+			strict:     *strict,
+			impl:       *impl,
+			allKeys:    *allKeys,
+			jsonOutput: *jsonOutput,
+			preview:    defaultPreviewLength,
+			template:   defaultOutputTemplate,
+			output:     output,
+		})
 	}
+}
 
-	// If check mode is enabled, just check the format
-	if *checkFormat {
-		data, err := os.ReadFile(*inputFile)
-		if err != nil {
-			fmt.Printf("Error reading file: %s\n", err)
-			os.Exit(1)
+// statusWriter returns where to print progress, keeping stdout clean when the key is written to it
+func statusWriter(outputPath string) io.Writer {
+	if isStdout(outputPath) {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// isStdout reports whether an output path names stdout, as "-" or its device file
+func isStdout(outputPath string) bool {
+	return outputPath == "-" || outputPath == "/dev/stdout"
+}
+
+// readInput reads key data from a file, or from stdin when path is "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// loadInput reads the key data of a command, with a clear message when the file is missing
+func loadInput(path string) ([]byte, error) {
+	if path != "-" {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, &ioError{fmt.Errorf("input file '%s' does not exist", path)}
 		}
+	}
+
+	data, err := readInput(path)
+	if err != nil {
+		return nil, &ioError{fmt.Errorf("failed to read input: %w", err)}
+	}
+
+	// Key archives may be gzip-compressed
+	return i2pkeys.DecompressKeyData(data)
+}
 
-		if i2pkeys.IsCorrectFormat(string(data)) {
-			fmt.Println("File IS in the correct two-line format")
-			os.Exit(0)
-		} else {
-			fmt.Println("File is NOT in the correct two-line format")
-			os.Exit(1)
+// Extensions that name text key files and binary key files; any other name says nothing
+var (
+	textKeyExtensions   = []string{".b64", ".base64", ".i2pb64", ".hex", ".txt", ".pem", ".formatted"}
+	binaryKeyExtensions = []string{".dat", ".bin"}
+)
+
+// extensionMismatch describes how the content of an input disagrees with its file name,
+// or returns "" when they agree or the name gives no hint. This is only a heuristic, so the
+// caller warns rather than fails.
+func extensionMismatch(path string, data []byte) string {
+	// The content has been decompressed already
+	name := strings.TrimSuffix(strings.ToLower(path), ".gz")
+	ext := filepath.Ext(name)
+	text := looksLikeText(data)
+
+	switch {
+	case slices.Contains(textKeyExtensions, ext) && !text:
+		return fmt.Sprintf("%s is named like a text key but holds binary data, is it the right file?", path)
+	case slices.Contains(binaryKeyExtensions, ext) && text && i2pkeys.IsCorrectFormat(string(data)):
+		return fmt.Sprintf("%s is named like a binary key but is already in the two-line format", path)
+	case slices.Contains(binaryKeyExtensions, ext) && text:
+		return fmt.Sprintf("%s is named like a binary key but holds text, is it the right file?", path)
+	}
+	return ""
+}
+
+// looksLikeText reports whether data is UTF-8 without control characters other than line
+// breaks and tabs. Random key bytes almost never pass: 387 of them all being printable is
+// vanishingly unlikely.
+func looksLikeText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
 		}
 	}
+	return true
+}
+
+// Inputs larger than this are almost certainly not key files; a keypair is at most a few KB
+const maxInputSize = 64 << 10
 
-	// Set default output file if not specified
-	if *outputFile == "" {
-		baseName := filepath.Base(*inputFile)
-		dir := filepath.Dir(*inputFile)
-		*outputFile = filepath.Join(dir, baseName+".formatted")
+// errInputTooLarge means the input is far larger than any key file
+var errInputTooLarge = errors.New("input is too large to be a key file")
+
+// checkInputSize refuses input larger than maxInputSize unless force is set, so pointing -in
+// at the wrong file doesn't produce a meaningless key cut from its first bytes
+func checkInputSize(data []byte, force bool) error {
+	if force || len(data) <= maxInputSize {
+		return nil
 	}
+	return fmt.Errorf("%w: %d bytes, more than %d (use -force if it really holds keys)", errInputTooLarge, len(data), maxInputSize)
+}
 
-	// Print operation info
-	fmt.Printf("Formatting I2P key file: %s\n", *inputFile)
-	fmt.Printf("Output file: %s\n", *outputFile)
+// outputOptions controls how writeOutput treats its target
+type outputOptions struct {
+	force  bool // Replace an existing output file
+	dryRun bool // Only check that the write would be allowed
+	quiet  bool // Print only the output path on success, with warnings and errors on stderr
 
-	// Convert the key file
-	err := i2pkeys.ConvertKeyFile(*inputFile, *outputFile)
-	if err != nil {
-		fmt.Printf("Error: %s\n", err)
-		os.Exit(1)
+	file i2pkeys.Options // Permissions and compression of written files
+}
+
+// statusWriter is where a run writing to outputPath prints its status; stderr when quiet,
+// so stdout holds nothing but the result
+func (o outputOptions) statusWriter(outputPath string) io.Writer {
+	if o.quiet {
+		return os.Stderr
 	}
+	return statusWriter(outputPath)
+}
 
-	// Verify the result
-	resultData, err := os.ReadFile(*outputFile)
-	if err != nil {
-		fmt.Printf("Error reading result file: %s\n", err)
-		os.Exit(1)
-	}
-
-	if i2pkeys.IsCorrectFormat(string(resultData)) {
-		fmt.Println("Conversion successful - key is now in the correct format")
-
-		// Display additional information if verbose mode is enabled
-		if *verbose {
-			lines := strings.Split(string(resultData), "\n")
-			if len(lines) >= 2 {
-				publicKeyPreview := truncateString(lines[0], 40)
-				fullKeyPreview := truncateString(lines[1], 40)
-
-				fmt.Println("\nKey Information:")
-				fmt.Printf("- Destination (public key): %s...\n", publicKeyPreview)
-				fmt.Printf("- Full key length: %d characters\n", len(lines[1]))
-				fmt.Printf("- Full key preview: %s...\n", fullKeyPreview)
-				fmt.Println("\nFormat: Two lines")
-				fmt.Println("- Line 1: Base64-encoded destination (public key)")
-				fmt.Println("- Line 2: Base64-encoded full keypair (public + private)")
+// printResult prints the output path in quiet mode, unless the output itself went to stdout
+func (o outputOptions) printResult(outputPath string) {
+	if o.quiet && !isStdout(outputPath) {
+		fmt.Println(outputPath)
+	}
+}
+
+// writeOutput writes formatted key data to a file, or to stdout when path is "-"
+func writeOutput(path string, data []byte, opts outputOptions) error {
+	if path == "-" {
+		if opts.dryRun {
+			return nil
+		}
+		if opts.file.Gzip {
+			compressed, err := i2pkeys.CompressKeyData(data)
+			if err != nil {
+				return err
 			}
+			data = compressed
+		}
+		if _, err := os.Stdout.Write(data); err != nil {
+			return &ioError{fmt.Errorf("failed to write to stdout: %w", err)}
 		}
-	} else {
-		fmt.Println("Warning: Output file is not in the correct format")
-		os.Exit(1)
+		return nil
+	}
+
+	// Refuse to clobber an existing key by accident; pipes and devices are written to, not replaced
+	if !opts.force {
+		if info, err := os.Stat(path); err == nil && (info.Mode().IsRegular() || info.IsDir()) {
+			return &ioError{fmt.Errorf("output file '%s' already exists (use -force to overwrite)", path)}
+		}
+	}
+
+	if opts.dryRun {
+		return nil
+	}
+
+	if err := i2pkeys.WriteKeyFile(path, data, opts.file); err != nil {
+		return &ioError{err}
 	}
+	return nil
 }
 
-// truncateString truncates a string and adds ellipsis if needed
+// truncateString truncates a string and adds ellipsis if needed. A maxLen of 0 keeps all of it.
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if maxLen <= 0 || len(s) <= maxLen {
 		return s
 	}
 	return s[:maxLen] + "..."
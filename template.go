@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Output template used when -template is not given: the input's name plus .formatted
+const defaultOutputTemplate = "{base}.formatted"
+
+// expandOutputTemplate names the output for inputFile from a template with these placeholders:
+//
+//	{dir}   directory of the input file
+//	{base}  file name of the input, extension included
+//	{name}  file name of the input without its extension
+//	{ext}   extension of the input, with its dot, or empty
+//
+// A result that is relative and doesn't start from {dir} is placed in outDir, or beside the
+// input when outDir is empty.
+func expandOutputTemplate(template, inputFile, outDir string) string {
+	dir := filepath.Dir(inputFile)
+	base := filepath.Base(inputFile)
+	ext := filepath.Ext(base)
+
+	expanded := strings.NewReplacer(
+		"{dir}", dir,
+		"{base}", base,
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", ext,
+	).Replace(template)
+
+	if !strings.Contains(template, "{dir}") && !filepath.IsAbs(expanded) {
+		if outDir == "" {
+			outDir = dir
+		}
+		expanded = filepath.Join(outDir, expanded)
+	}
+	return filepath.Clean(expanded)
+}